@@ -0,0 +1,167 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Role is the level of access a user has on a connection. Each role includes the ones below it.
+type Role string
+
+const (
+	RoleNone   Role = ""
+	RoleViewer Role = "viewer" // Browse rows, columns and the schema, run queries.
+	RoleEditor Role = "editor" // Viewer plus row inserts, updates and deletes.
+	RoleAdmin  Role = "admin"  // Editor plus DDL, /api/exec and connection management.
+)
+
+var ErrForbidden = errors.New("permission denied")
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Allows reports whether r includes the permissions of required.
+func (r Role) Allows(required Role) bool {
+	return r.rank() >= required.rank() && r.rank() > 0
+}
+
+func (r Role) valid() bool {
+	return r == RoleNone || r.rank() > 0
+}
+
+// Grant is a user's role on every connection, optionally overridden per connection.
+// Only the global role counts for managing connections.
+type Grant struct {
+	Role        Role            `json:"role"`
+	Connections map[string]Role `json:"connections,omitempty"`
+}
+
+// AccessControl maps authenticated users to roles. Users without a grant have no access;
+// the access-token user is an admin unless the config lists it explicitly.
+type AccessControl struct {
+	users map[string]Grant
+}
+
+func NewAccessControl(users map[string]Grant) (*AccessControl, error) {
+	for name, grant := range users {
+		if !grant.Role.valid() {
+			return nil, fmt.Errorf("user %s: unknown role %q", name, grant.Role)
+		}
+		for conn, role := range grant.Connections {
+			if !role.valid() {
+				return nil, fmt.Errorf("user %s, connection %s: unknown role %q", name, conn, role)
+			}
+		}
+	}
+	return &AccessControl{users: users}, nil
+}
+
+// LoadAccessFile reads grants from a JSON file of the form
+//
+//	{"users": {"alice": {"role": "admin"}, "bob": {"role": "viewer", "connections": {"dev": "editor"}}}}
+func LoadAccessFile(path string) (*AccessControl, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Users map[string]Grant `json:"users"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewAccessControl(config.Users)
+}
+
+func (a *AccessControl) grant(user string) (Grant, bool) {
+	grant, ok := a.users[user]
+	if !ok && user == tokenUser {
+		return Grant{Role: RoleAdmin}, true
+	}
+	return grant, ok
+}
+
+// Role returns the role user has on the named connection.
+func (a *AccessControl) Role(user, connection string) Role {
+	grant, ok := a.grant(user)
+	if !ok {
+		return RoleNone
+	}
+	if role, ok := grant.Connections[connection]; ok {
+		return role
+	}
+	return grant.Role
+}
+
+// GlobalRole returns the role user has regardless of connection.
+func (a *AccessControl) GlobalRole(user string) Role {
+	grant, _ := a.grant(user)
+	return grant.Role
+}
+
+// allow rejects requests whose user lacks required on the connection selected by ?db= with 403.
+func (api *API) allow(required Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.access != nil {
+			name := api.connectionName(r)
+			if !api.roleOn(Principal(r.Context()), name).Allows(required) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("%w: %s access to %s required", ErrForbidden, required, name))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// roleOn returns user's role on the named connection. Derived connections share the role of
+// the connection they were opened from.
+func (api *API) roleOn(user, name string) Role {
+	if base := api.connections.Base(name); base != "" {
+		name = base
+	}
+	return api.access.Role(user, name)
+}
+
+// canWrite reports whether the user of r may run statements that change data on the
+// connection r addresses, which takes admin access as for /api/exec.
+func (api *API) canWrite(r *http.Request) bool {
+	return api.access == nil || api.roleOn(Principal(r.Context()), api.connectionName(r)).Allows(RoleAdmin)
+}
+
+// allowGlobal rejects requests whose user lacks required as their connection-independent role.
+func (api *API) allowGlobal(required Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.access != nil && !api.access.GlobalRole(Principal(r.Context())).Allows(required) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%w: %s role required", ErrForbidden, required))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// visibleConnections filters conns down to those the request's user may view.
+func (api *API) visibleConnections(r *http.Request, conns []ConnectionInfo) []ConnectionInfo {
+	if api.access == nil {
+		return conns
+	}
+	user := Principal(r.Context())
+	visible := make([]ConnectionInfo, 0, len(conns))
+	for _, conn := range conns {
+		if api.roleOn(user, conn.Name).Allows(RoleViewer) {
+			visible = append(visible, conn)
+		}
+	}
+	return visible
+}
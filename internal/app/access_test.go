@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestAccessControlRoles(t *testing.T) {
+	api := newTestAPI(t)
+	if err := api.connections.Add(context.Background(), "dev", ":memory:"); err != nil {
+		t.Fatalf("add dev: %v", err)
+	}
+	dev, _ := api.connections.Get("dev")
+	api.exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)")
+	if _, err := dev.Exec(context.Background(), "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	access, err := NewAccessControl(map[string]Grant{
+		"viewer": {Role: RoleViewer, Connections: map[string]Role{"dev": RoleEditor}},
+		"admin":  {Role: RoleAdmin},
+		"devops": {Connections: map[string]Role{"dev": RoleAdmin}},
+	})
+	if err != nil {
+		t.Fatalf("access control: %v", err)
+	}
+	api.SetAccessControl(access)
+
+	cases := []struct {
+		user, method, target, body string
+		want                       int
+	}{
+		{"viewer", http.MethodGet, "/api/tables/items/rows", "", http.StatusOK},
+		{"viewer", http.MethodPost, "/api/tables/items/rows", `{"name":"a"}`, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/tables/items/rows?db=dev", `{"name":"a"}`, http.StatusCreated},
+		{"viewer", http.MethodDelete, "/api/tables/items?db=dev", "", http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/connections", `{"connString":":memory:"}`, http.StatusForbidden},
+		{"devops", http.MethodGet, "/api/tables", "", http.StatusForbidden},
+		{"devops", http.MethodPost, "/api/exec?db=dev", `{"query":"DELETE FROM items"}`, http.StatusOK},
+		{"devops", http.MethodPost, "/api/connections", `{"connString":":memory:"}`, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/query", `{"query":"SELECT * FROM items"}`, http.StatusOK},
+		{"viewer", http.MethodPost, "/api/query", `{"query":"DELETE FROM items WHERE id = 3"}`, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/query?db=dev", `{"query":"DELETE FROM items RETURNING id"}`, http.StatusForbidden},
+		{"devops", http.MethodPost, "/api/query?db=dev", `{"query":"DELETE FROM items RETURNING id"}`, http.StatusOK},
+		{"viewer", http.MethodPost, "/api/query", `{"query":"SELECT 1; PRAGMA query_only = 0; DELETE FROM items"}`, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/query", `{"query":"SELECT 1; SELECT 2"}`, http.StatusBadRequest},
+		{"viewer", http.MethodPost, "/api/console", `{"script":"SELECT * FROM items"}`, http.StatusOK},
+		{"viewer", http.MethodPost, "/api/console", `{"script":"DELETE FROM items WHERE id = 3 RETURNING id"}`, http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/console", `{"script":"PRAGMA query_only = 0"}`, http.StatusForbidden},
+		{"stranger", http.MethodGet, "/api/tables", "", http.StatusForbidden},
+		{tokenUser, http.MethodDelete, "/api/tables/items", "", http.StatusOK},
+		{"admin", http.MethodPost, "/api/connections", `{"name":"extra","connString":":memory:"}`, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		if got := api.doAs(tc.user, tc.method, tc.target, tc.body).Code; got != tc.want {
+			t.Errorf("%s %s %s: got %d, want %d", tc.user, tc.method, tc.target, got, tc.want)
+		}
+	}
+
+	if _, err := NewAccessControl(map[string]Grant{"x": {Role: "owner"}}); err == nil {
+		t.Fatalf("expected an unknown role to be rejected")
+	}
+}
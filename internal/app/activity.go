@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"sqlite-gui/pkg/database/postgresql"
+)
+
+var ErrActivityUnsupported = errors.New("the activity monitor is only supported on PostgreSQL connections")
+
+type activityMonitor interface {
+	Activity(ctx context.Context) ([]postgresql.Backend, error)
+	Locks(ctx context.Context) ([]postgresql.Lock, error)
+	CancelBackend(ctx context.Context, pid int, terminate bool) (bool, error)
+}
+
+func (api *API) useActivityMonitor(w http.ResponseWriter, r *http.Request) (activityMonitor, bool) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return nil, false
+	}
+	m, ok := db.(activityMonitor)
+	if !ok {
+		writeError(w, http.StatusBadRequest, ErrActivityUnsupported)
+		return nil, false
+	}
+	return m, true
+}
+
+// getActivity lists the server's backends from pg_stat_activity and the locks from pg_locks.
+// A backend's blockedBy names the pids whose locks it waits for.
+// curl: curl -X GET "http://localhost:3000/api/activity?db=pg"
+func (api *API) getActivity(w http.ResponseWriter, r *http.Request) {
+	m, ok := api.useActivityMonitor(w, r)
+	if !ok {
+		return
+	}
+	backends, err := m.Activity(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	locks, err := m.Locks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"backends": backends, "locks": locks})
+}
+
+// cancelBackend cancels the running query of a backend with pg_cancel_backend.
+// curl: curl -X POST "http://localhost:3000/api/activity/12345/cancel?db=pg"
+func (api *API) cancelBackend(w http.ResponseWriter, r *http.Request) {
+	api.signalBackend(w, r, false)
+}
+
+// terminateBackend ends a backend's session with pg_terminate_backend.
+// curl: curl -X POST "http://localhost:3000/api/activity/12345/terminate?db=pg"
+func (api *API) terminateBackend(w http.ResponseWriter, r *http.Request) {
+	api.signalBackend(w, r, true)
+}
+
+func (api *API) signalBackend(w http.ResponseWriter, r *http.Request, terminate bool) {
+	m, ok := api.useActivityMonitor(w, r)
+	if !ok {
+		return
+	}
+	pid, err := strconv.Atoi(r.PathValue("pid"))
+	if err != nil || pid <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pid %q", r.PathValue("pid")))
+		return
+	}
+	signalled, err := m.CancelBackend(r.Context(), pid, terminate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !signalled {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no backend with pid %d", pid))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestActivityRequiresPostgres(t *testing.T) {
+	api := newTestAPI(t)
+	for _, target := range []struct{ method, path string }{
+		{http.MethodGet, "/api/activity"},
+		{http.MethodPost, "/api/activity/42/terminate"},
+	} {
+		rec := api.do(target.method, target.path, "")
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "PostgreSQL") {
+			t.Fatalf("%s %s: expected 400 for SQLite, got %d %s", target.method, target.path, rec.Code, rec.Body)
+		}
+	}
+}
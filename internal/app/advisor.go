@@ -0,0 +1,67 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"sqlite-gui/pkg/database"
+)
+
+type advisedQuery struct {
+	Query string `json:"query"`
+	Args  []any  `json:"args"`
+}
+
+// suggestIndexes proposes indexes for one query, or several given as "queries", that would
+// avoid full table scans and sorts. With "slow": true the connection's recent slow queries
+// are analysed as well. Apply a suggestion by posting its "index" to
+// /api/tables/{table}/indexes.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"query":"SELECT * FROM users WHERE email = ?","args":["a@b.c"]}' "http://localhost:3000/api/advisor/indexes?db=db1"
+func (api *API) suggestIndexes(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		advisedQuery
+		Queries []advisedQuery `json:"queries"`
+		Slow    bool           `json:"slow"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	queries := req.Queries
+	if req.Query != "" {
+		queries = append(queries, req.advisedQuery)
+	}
+	if req.Slow {
+		for _, q := range api.recentSlowQueries(r, api.connectionName(r)) {
+			if q.Kind == "query" && q.Error == "" {
+				queries = append(queries, advisedQuery{Query: q.Statement, Args: q.Args})
+			}
+		}
+	} else if len(queries) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("query or queries is required"))
+		return
+	}
+
+	suggestions := []database.IndexSuggestion{}
+	seen := make(map[string]bool)
+	for _, q := range queries {
+		found, err := db.SuggestIndexes(r.Context(), q.Query, q.Args...)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s: %w", q.Query, err))
+			return
+		}
+		for _, s := range found {
+			if !seen[s.Statement] {
+				seen[s.Statement] = true
+				suggestions = append(suggestions, s)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"suggestions": suggestions})
+}
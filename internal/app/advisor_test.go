@@ -0,0 +1,45 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"sqlite-gui/pkg/database"
+)
+
+func TestSuggestAndApplyIndex(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT, age INTEGER)")
+
+	suggest := func() []database.IndexSuggestion {
+		body := `{"queries":[{"query":"SELECT * FROM users u WHERE u.email = ? ORDER BY name","args":["a@b.c"]},{"query":"SELECT 1"}]}`
+		rec := api.do(http.MethodPost, "/api/advisor/indexes", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("suggest: %d %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			Suggestions []database.IndexSuggestion `json:"suggestions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Suggestions
+	}
+
+	suggestions := suggest()
+	if len(suggestions) != 1 || suggestions[0].Table != "users" || !suggestions[0].Verified ||
+		strings.Join(suggestions[0].Index.Columns, ",") != "email,name" {
+		t.Fatalf("expected an index on users (email, name), got %+v", suggestions)
+	}
+
+	index, _ := json.Marshal(suggestions[0].Index)
+	rec := api.do(http.MethodPost, "/api/tables/users/indexes", string(index))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("apply: %d %s", rec.Code, rec.Body)
+	}
+	if again := suggest(); len(again) != 0 {
+		t.Fatalf("expected no suggestions once the index exists, got %+v", again)
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqlite-gui/pkg/database"
+)
+
+// testAPI is an API with the connection "main", serving its routes on mux.
+type testAPI struct {
+	*API
+	t   *testing.T
+	mux *http.ServeMux
+	db  database.Database // The "main" connection.
+}
+
+// newTestAPI returns a testAPI whose main connection is a fresh in-memory SQLite database.
+// Connections are closed when the test ends.
+func newTestAPI(t *testing.T) *testAPI {
+	return newTestAPIOn(t, ":memory:", ConnectionOptions{})
+}
+
+// newTestAPIOn is newTestAPI with main opened from connString with opts.
+func newTestAPIOn(t *testing.T, connString string, opts ConnectionOptions) *testAPI {
+	t.Helper()
+	mgr := NewConnectionManager()
+	t.Cleanup(func() {
+		_ = mgr.CloseAll()
+	})
+	if err := mgr.AddWithOptions(context.Background(), "main", connString, opts); err != nil {
+		t.Fatalf("add main: %v", err)
+	}
+	db, _ := mgr.Get("main")
+	api := &testAPI{API: NewAPI(mgr), t: t, mux: http.NewServeMux(), db: db}
+	api.RegisterRoutes(api.mux)
+	return api
+}
+
+// exec runs stmts on main.
+func (a *testAPI) exec(stmts ...string) {
+	a.t.Helper()
+	for _, stmt := range stmts {
+		if _, err := a.db.Exec(context.Background(), stmt); err != nil {
+			a.t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+// do serves a request with body and the given header name/value pairs.
+func (a *testAPI) do(method, target, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	a.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// doAs serves a request made by the authenticated user.
+func (a *testAPI) doAs(user, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), principalKey{}, user))
+	rec := httptest.NewRecorder()
+	a.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// useMetadata keeps the query history and saved queries in a metadata file of the test.
+func (a *testAPI) useMetadata() *Metadata {
+	a.t.Helper()
+	metadata, err := OpenMetadata(context.Background(), filepath.Join(a.t.TempDir(), "meta.db"))
+	if err != nil {
+		a.t.Fatalf("open metadata: %v", err)
+	}
+	a.t.Cleanup(func() {
+		_ = metadata.Close()
+	})
+	a.SetMetadata(metadata)
+	return metadata
+}
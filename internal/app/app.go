@@ -10,14 +10,24 @@ import (
 	"os"
 	"path/filepath"
 	svelte "sqlite-gui"
+	"strconv"
 	"strings"
 )
 
 const defaultConnectionString = "main=file:sqlite-gui.db?_pragma=foreign_keys(1)"
 
 var (
-	port    = flag.Int("port", 3000, "The server port")
-	dbPaths dbFlag
+	host       = flag.String("host", "127.0.0.1", "The interface to listen on (use 0.0.0.0 to listen on all interfaces)")
+	port       = flag.Int("port", 3000, "The server port")
+	token      = flag.String("token", "", "Access token required by the server (a random one is generated when empty)")
+	usersFile  = flag.String("auth-file", "", "htpasswd-style file of name:bcrypt-hash users allowed to log in with basic auth")
+	noAuth     = flag.Bool("no-auth", false, "Disable authentication (only for trusted local use)")
+	accessFile = flag.String("access-file", "", "JSON file of per-user roles (viewer, editor, admin) and per-connection grants")
+	auditFile  = flag.String("audit-file", "", "SQLite file that records every data and schema change (disabled when empty)")
+	metaFile   = flag.String("metadata-file", "", "SQLite file that keeps the query history and saved queries (disabled when empty)")
+	slowQuery  = flag.Duration("slow-query-threshold", defaultSlowQueryThreshold, "Statements running at least this long are listed by /api/slow-queries (0 disables)")
+	dbPaths    dbFlag
+	authUsers  dbFlag
 )
 
 type dbFlag []string
@@ -40,7 +50,8 @@ func init() {
 		flag.PrintDefaults()
 		fmt.Fprintf(w, "\nFor more information, visit https://github.com/GNITOAHC/sqlite-gui.\n")
 	}
-	flag.Var(&dbPaths, "db", "Connection string (repeatable). Format name=connStr to label the connection.")
+	flag.Var(&dbPaths, "db", "Connection string (repeatable). Format name=connStr to label the connection, append ;readonly to reject writes.")
+	flag.Var(&authUsers, "auth-user", "Basic auth user as name:bcrypt-hash, e.g. from `htpasswd -nB name` (repeatable)")
 }
 
 func Run() {
@@ -52,33 +63,117 @@ func Run() {
 	ctx := context.Background()
 	manager := NewConnectionManager()
 	for i, raw := range dbPaths {
+		raw, opts, err := splitConnectionFlags(raw)
+		if err != nil {
+			log.Fatalf("invalid connection %q: %v", dbPaths[i], err)
+		}
 		name, conn := parseConnectionArg(raw, fmt.Sprintf("db%d", i+1))
-		if err := manager.Add(ctx, name, conn); err != nil {
+		if err := manager.AddWithOptions(ctx, name, conn, opts); err != nil {
 			log.Fatalf("failed to connect to database %q: %v", conn, err)
 		}
-		log.Printf("Connected to %q (%s)", name, conn)
+		if opts.ReadOnly {
+			log.Printf("Connected to %q (%s, read-only)", name, conn)
+		} else {
+			log.Printf("Connected to %q (%s)", name, conn)
+		}
 	}
 	defer manager.CloseAll()
 
 	api := NewAPI(manager)
+	auth, err := newAuthenticatorFromFlags()
+	if err != nil {
+		log.Fatalf("failed to configure authentication: %v", err)
+	}
+	if *accessFile != "" {
+		if auth == nil {
+			log.Fatalf("-access-file requires authentication (remove -no-auth)")
+		}
+		access, err := LoadAccessFile(*accessFile)
+		if err != nil {
+			log.Fatalf("failed to load access file: %v", err)
+		}
+		api.SetAccessControl(access)
+	}
+	if *auditFile != "" {
+		auditLog, err := OpenAuditLog(ctx, *auditFile)
+		if err != nil {
+			log.Fatalf("failed to open audit log: %v", err)
+		}
+		defer auditLog.Close()
+		api.SetAuditLog(auditLog)
+		log.Printf("Recording changes to audit log %s", *auditFile)
+	}
+	if *metaFile != "" {
+		metadata, err := OpenMetadata(ctx, *metaFile)
+		if err != nil {
+			log.Fatalf("failed to open metadata file: %v", err)
+		}
+		defer metadata.Close()
+		api.SetMetadata(metadata)
+	}
+	api.SetSlowQueryThreshold(*slowQuery)
 
 	// ROUTES DEFINITION START
 	mux := http.NewServeMux()
 	handle(mux, "GET /ping", http.HandlerFunc(pong))
+	if auth != nil {
+		auth.RegisterRoutes(mux)
+	}
 	api.RegisterRoutes(mux)
 	handle(mux, "/", svelte.FileServer()) // SSG file server (should be last route)
-	handler := corsMiddleware(mux)
+	var handler http.Handler = schemaMiddleware(mux)
+	if auth != nil {
+		handler = auth.Middleware(handler)
+	}
+	handler = sameOriginMiddleware(handler)
+	handler = corsMiddleware(handler)
 	// ROUTES DEFINITION END
 
-	log.Printf("Starting server on port %d", *port)
+	addr := net.JoinHostPort(*host, strconv.Itoa(*port))
+	log.Printf("Starting server on %s", addr)
+	if auth != nil {
+		log.Printf("Open http://%s/?token=%s", addr, auth.token)
+	} else {
+		log.Printf("Authentication is disabled")
+	}
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 	log.Fatal(http.Serve(lis, handler))
 }
 
+// newAuthenticatorFromFlags builds the authenticator from -token, -auth-user and -auth-file.
+// It returns nil when -no-auth is set.
+func newAuthenticatorFromFlags() (*Authenticator, error) {
+	if *noAuth {
+		return nil, nil
+	}
+	accessToken := *token
+	if accessToken == "" {
+		generated, err := GenerateToken()
+		if err != nil {
+			return nil, err
+		}
+		accessToken = generated
+	}
+	users, err := ParseUsers(authUsers)
+	if err != nil {
+		return nil, err
+	}
+	if *usersFile != "" {
+		fileUsers, err := LoadUsersFile(*usersFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, hash := range fileUsers {
+			users[name] = hash
+		}
+	}
+	return NewAuthenticator(accessToken, users), nil
+}
+
 func parseConnectionArg(raw, fallbackName string) (string, string) {
 	if parts := strings.SplitN(raw, "=", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) != "" {
 		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
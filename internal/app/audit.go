@@ -0,0 +1,311 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sqlite-gui/pkg/database"
+	"sqlite-gui/pkg/database/sqlite"
+)
+
+const (
+	auditTimeFormat   = "2006-01-02T15:04:05.000000Z" // Fixed width so stored times sort as text.
+	defaultAuditLimit = 100
+)
+
+var ErrAuditDisabled = errors.New("audit log is disabled (start the server with -audit-file)")
+
+// AuditEntry is one recorded change. Row edits carry the key and the row images before and
+// after the change; schema changes carry the request payload in After; /api/exec carries
+// the statement and its arguments.
+type AuditEntry struct {
+	ID         int64        `json:"id"`
+	Time       time.Time    `json:"time"`
+	Connection string       `json:"connection"`
+	Action     string       `json:"action"`
+	Schema     string       `json:"schema,omitempty"`
+	Table      string       `json:"table,omitempty"`
+	Key        database.Key `json:"key,omitempty"`
+	User       string       `json:"user,omitempty"`
+	RemoteAddr string       `json:"remoteAddr"`
+	Before     any          `json:"before,omitempty"`
+	After      any          `json:"after,omitempty"`
+	Statement  string       `json:"statement,omitempty"`
+	Args       []any        `json:"args,omitempty"`
+}
+
+// AuditFilter narrows List results. Zero values match everything.
+type AuditFilter struct {
+	Connection string
+	Schema     string
+	Table      string
+	User       string
+	Action     string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditLog stores entries in a local SQLite file whose triggers reject UPDATE and DELETE.
+type AuditLog struct {
+	db database.Database
+}
+
+var auditSchema = []string{
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		time        TEXT NOT NULL,
+		connection  TEXT NOT NULL,
+		action      TEXT NOT NULL,
+		schema_name TEXT,
+		table_name  TEXT,
+		key         TEXT,
+		user        TEXT,
+		remote_addr TEXT,
+		before      TEXT,
+		after       TEXT,
+		statement   TEXT,
+		args        TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_time ON audit_log (time)`,
+	`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+	BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`,
+	`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+	BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`,
+}
+
+func OpenAuditLog(ctx context.Context, path string) (*AuditLog, error) {
+	db := sqlite.New()
+	if err := db.Connect(ctx, path); err != nil {
+		return nil, err
+	}
+	for _, stmt := range auditSchema {
+		if _, err := db.Exec(ctx, stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &AuditLog{db: db}, nil
+}
+
+func (l *AuditLog) Close() error {
+	return l.db.Close()
+}
+
+func (l *AuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	row := database.Row{
+		"time":        entry.Time.UTC().Format(auditTimeFormat),
+		"connection":  entry.Connection,
+		"action":      entry.Action,
+		"schema_name": nullString(entry.Schema),
+		"table_name":  nullString(entry.Table),
+		"user":        nullString(entry.User),
+		"remote_addr": nullString(entry.RemoteAddr),
+		"statement":   nullString(entry.Statement),
+	}
+	for column, value := range map[string]any{"key": entry.Key, "before": entry.Before, "after": entry.After, "args": entry.Args} {
+		encoded, err := encodeAuditValue(value)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", column, err)
+		}
+		row[column] = encoded
+	}
+	return l.db.Insert(ctx, "audit_log", row)
+}
+
+// List returns matching entries, newest first.
+func (l *AuditLog) List(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	var (
+		where []string
+		args  []any
+	)
+	for column, value := range map[string]string{
+		"connection":  filter.Connection,
+		"schema_name": filter.Schema,
+		"table_name":  filter.Table,
+		"user":        filter.User,
+		"action":      filter.Action,
+	} {
+		if value != "" {
+			where = append(where, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, filter.Since.UTC().Format(auditTimeFormat))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, filter.Until.UTC().Format(auditTimeFormat))
+	}
+	query := "SELECT * FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := l.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entry, err := decodeAuditRow(row)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func decodeAuditRow(row database.Row) (AuditEntry, error) {
+	text := func(column string) string {
+		s, _ := row[column].(string)
+		return s
+	}
+	entry := AuditEntry{
+		Connection: text("connection"),
+		Action:     text("action"),
+		Schema:     text("schema_name"),
+		Table:      text("table_name"),
+		User:       text("user"),
+		RemoteAddr: text("remote_addr"),
+		Statement:  text("statement"),
+	}
+	entry.ID, _ = row["id"].(int64)
+	t, err := time.Parse(auditTimeFormat, text("time"))
+	if err != nil {
+		return entry, err
+	}
+	entry.Time = t
+	for column, target := range map[string]any{"key": &entry.Key, "before": &entry.Before, "after": &entry.After, "args": &entry.Args} {
+		if raw := text(column); raw != "" {
+			if err := json.Unmarshal([]byte(raw), target); err != nil {
+				return entry, fmt.Errorf("decode %s of entry %d: %w", column, entry.ID, err)
+			}
+		}
+	}
+	return entry, nil
+}
+
+func encodeAuditValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case database.Key:
+		if v == nil {
+			return nil, nil
+		}
+	case database.Row:
+		if v == nil {
+			return nil, nil
+		}
+	case []any:
+		if v == nil {
+			return nil, nil
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// logChange records a change made by r in the audit log and broadcasts it to /api/events
+// subscribers. Audit failures are logged rather than returned, since the change itself has
+// already been applied.
+func (api *API) logChange(r *http.Request, entry AuditEntry) {
+	entry.Time = time.Now()
+	entry.Connection = api.connectionName(r)
+	if entry.Schema == "" {
+		entry.Schema = database.SchemaFromContext(r.Context())
+	}
+	entry.User = Principal(r.Context())
+	entry.RemoteAddr = r.RemoteAddr
+	api.events.Publish(ChangeEvent{
+		Connection: entry.Connection,
+		Schema:     entry.Schema,
+		Table:      entry.Table,
+		Action:     entry.Action,
+		Key:        entry.Key,
+		User:       entry.User,
+		Source:     "api",
+		Time:       entry.Time,
+	})
+	if api.auditLog == nil {
+		return
+	}
+	if err := api.auditLog.Record(r.Context(), entry); err != nil {
+		log.Printf("audit: failed to record %s on %s: %v", entry.Action, entry.Connection, err)
+	}
+}
+
+// updatedKey returns key with any primary key values changed by data applied.
+func updatedKey(key database.Key, data database.Row) database.Key {
+	next := make(database.Key, len(key))
+	for col, value := range key {
+		if changed, ok := data[col]; ok {
+			value = changed
+		}
+		next[col] = value
+	}
+	return next
+}
+
+// getAudit lists audit entries, newest first, filtered by connection, schema, table, user, action
+// and an RFC 3339 since/until time range.
+// curl: curl -X GET "http://localhost:3000/api/audit?connection=db1&table=users&since=2024-01-01T00:00:00Z&limit=50"
+func (api *API) getAudit(w http.ResponseWriter, r *http.Request) {
+	if api.auditLog == nil {
+		writeError(w, http.StatusNotFound, ErrAuditDisabled)
+		return
+	}
+	q := r.URL.Query()
+	filter := AuditFilter{
+		Connection: q.Get("connection"),
+		Schema:     q.Get("schema"),
+		Table:      q.Get("table"),
+		User:       q.Get("user"),
+		Action:     q.Get("action"),
+		Limit:      queryInt(r, "limit"),
+		Offset:     queryInt(r, "offset"),
+	}
+	for param, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := q.Get(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %w", param, err))
+				return
+			}
+			*target = t
+		}
+	}
+	entries, err := api.auditLog.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": entries})
+}
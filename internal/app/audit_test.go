@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogRecordsRowEdits(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	api.exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	auditLog, err := OpenAuditLog(ctx, filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = auditLog.Close()
+	})
+	api.SetAuditLog(auditLog)
+
+	for _, step := range []struct{ method, target, body string }{
+		{http.MethodPost, "/api/tables/users/rows", `{"id":1,"name":"alice"}`},
+		{http.MethodPut, "/api/tables/users/rows/1", `{"name":"alicia"}`},
+		{http.MethodDelete, "/api/tables/users/rows/1", ""},
+		{http.MethodPost, "/api/query", `{"query":"INSERT INTO users (name) VALUES ('bob') RETURNING id"}`},
+		{http.MethodPost, "/api/query", `{"query":"SELECT * FROM users"}`},
+	} {
+		if rec := api.do(step.method, step.target, step.body); rec.Code >= 300 {
+			t.Fatalf("%s %s: %d %s", step.method, step.target, rec.Code, rec.Body)
+		}
+	}
+
+	entries, err := auditLog.List(ctx, AuditFilter{Table: "users"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	update := entries[1]
+	if update.Action != "update" || update.Connection != "main" || update.RemoteAddr == "" {
+		t.Fatalf("unexpected update entry: %+v", update)
+	}
+	before, _ := update.Before.(map[string]any)
+	after, _ := update.After.(map[string]any)
+	if before["name"] != "alice" || after["name"] != "alicia" {
+		t.Fatalf("unexpected row images: before=%v after=%v", update.Before, update.After)
+	}
+	if deleted, _ := entries[0].Before.(map[string]any); entries[0].Action != "delete" || deleted["name"] != "alicia" {
+		t.Fatalf("unexpected delete entry: %+v", entries[0])
+	}
+
+	if entries, err := auditLog.List(ctx, AuditFilter{Action: "insert"}); err != nil || len(entries) != 1 {
+		t.Fatalf("expected one insert entry, got %d (%v)", len(entries), err)
+	}
+	execs, err := auditLog.List(ctx, AuditFilter{Action: "exec"})
+	if err != nil || len(execs) != 1 || !strings.Contains(execs[0].Statement, "RETURNING") {
+		t.Fatalf("expected the RETURNING insert to be audited, got %+v (%v)", execs, err)
+	}
+	if _, err := auditLog.db.Exec(ctx, "DELETE FROM audit_log"); err == nil {
+		t.Fatalf("expected the audit log to reject deletes")
+	}
+}
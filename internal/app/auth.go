@@ -0,0 +1,302 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookie = "sqlite_gui_session"
+	sessionTTL    = 7 * 24 * time.Hour
+	tokenUser     = "token" // Principal name for requests authenticated with the access token.
+)
+
+var ErrUnauthorized = errors.New("authentication required")
+
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// Authenticator protects the server with an access token and optional basic-auth users.
+// Browsers exchange either credential for a session cookie through the login page.
+type Authenticator struct {
+	token string
+	users map[string][]byte // username -> bcrypt hash
+
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+type principalKey struct{}
+
+// keyOnlyKey marks requests the middleware let through unauthenticated for their API key.
+type keyOnlyKey struct{}
+
+func NewAuthenticator(token string, users map[string][]byte) *Authenticator {
+	return &Authenticator{
+		token:    token,
+		users:    users,
+		sessions: make(map[string]session),
+	}
+}
+
+// Principal returns the authenticated user name stored in ctx by the auth middleware.
+func Principal(ctx context.Context) string {
+	user, _ := ctx.Value(principalKey{}).(string)
+	return user
+}
+
+// GenerateToken returns a random hex-encoded access token.
+func GenerateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ParseUsers reads "name:bcrypt-hash" entries, as produced by `htpasswd -nB name`.
+func ParseUsers(entries []string) (map[string][]byte, error) {
+	users := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		name, hash, ok := strings.Cut(entry, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid user entry %q (want name:bcrypt-hash)", entry)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("user %s: %w", name, err)
+		}
+		users[name] = []byte(hash)
+	}
+	return users, nil
+}
+
+// LoadUsersFile reads an htpasswd-style file with one bcrypt "name:hash" entry per line.
+func LoadUsersFile(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entries = append(entries, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParseUsers(entries)
+}
+
+func (a *Authenticator) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", a.loginPage)
+	mux.HandleFunc("POST /login", a.login)
+	mux.HandleFunc("POST /logout", a.logout)
+}
+
+// Middleware rejects unauthenticated requests: API calls get 401, page loads are sent to the login page.
+// A valid ?token= on a page load starts a session and redirects to the same URL without the token.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" || r.URL.Path == "/ping" || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if user, ok := a.authenticate(r); ok {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, user)))
+			return
+		}
+		if token := r.URL.Query().Get("token"); token != "" && a.checkToken(token) {
+			if err := a.startSession(w, tokenUser); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			clean := *r.URL
+			query := clean.Query()
+			query.Del("token")
+			clean.RawQuery = query.Encode()
+			http.Redirect(w, r, clean.RequestURI(), http.StatusSeeOther)
+			return
+		}
+		published := strings.HasPrefix(r.URL.Path, "/q/")
+		if published && r.Header.Get(apiKeyHeader) != "" {
+			// Published queries with an API key check it themselves.
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyOnlyKey{}, true)))
+			return
+		}
+		if published || strings.HasPrefix(r.URL.Path, "/api/") {
+			if len(a.users) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="sqlite-gui"`)
+			}
+			writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+// authenticate checks, in order, the session cookie, a bearer token and basic-auth credentials.
+func (a *Authenticator) authenticate(r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if user, ok := a.lookupSession(cookie.Value); ok {
+			return user, true
+		}
+	}
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && a.checkToken(bearer) {
+		return tokenUser, true
+	}
+	if name, password, ok := r.BasicAuth(); ok && a.checkPassword(name, password) {
+		return name, true
+	}
+	return "", false
+}
+
+func (a *Authenticator) checkToken(token string) bool {
+	return a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+func (a *Authenticator) checkPassword(name, password string) bool {
+	hash, ok := a.users[name]
+	return ok && bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+func (a *Authenticator) startSession(w http.ResponseWriter, user string) error {
+	id, err := GenerateToken()
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(sessionTTL)
+	a.mu.Lock()
+	a.sessions[id] = session{user: user, expires: expires}
+	a.mu.Unlock()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (a *Authenticator) lookupSession(id string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.sessions[id]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, id)
+		return "", false
+	}
+	return s.user, true
+}
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>sqlite-gui login</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; justify-content: center; margin-top: 10vh; }
+form { display: flex; flex-direction: column; gap: .5rem; width: 20rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<form method="post" action="/login">
+<h1>sqlite-gui</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="hidden" name="next" value="{{.Next}}">
+{{if .Users}}
+<label>Username <input name="username" autocomplete="username"></label>
+<label>Password <input name="password" type="password" autocomplete="current-password"></label>
+<p>or</p>
+{{end}}
+<label>Access token <input name="token" type="password" autocomplete="off"></label>
+<button type="submit">Log in</button>
+</form>
+</body>
+</html>
+`))
+
+func (a *Authenticator) renderLogin(w http.ResponseWriter, status int, next, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = loginTemplate.Execute(w, map[string]any{
+		"Next":  next,
+		"Error": message,
+		"Users": len(a.users) > 0,
+	})
+}
+
+// loginPage serves the HTML login form.
+func (a *Authenticator) loginPage(w http.ResponseWriter, r *http.Request) {
+	a.renderLogin(w, http.StatusOK, r.URL.Query().Get("next"), "")
+}
+
+// login exchanges a token or username/password for a session cookie.
+// curl: curl -i -X POST -d "token=<token>" http://localhost:3000/login
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	next := r.PostForm.Get("next")
+	// Only follow local redirects so the login form cannot be used to bounce users elsewhere.
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = "/"
+	}
+
+	var user string
+	switch {
+	case r.PostForm.Get("token") != "" && a.checkToken(r.PostForm.Get("token")):
+		user = tokenUser
+	case r.PostForm.Get("username") != "" && a.checkPassword(r.PostForm.Get("username"), r.PostForm.Get("password")):
+		user = r.PostForm.Get("username")
+	default:
+		a.renderLogin(w, http.StatusUnauthorized, next, "Invalid credentials")
+		return
+	}
+	if err := a.startSession(w, user); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// logout ends the current session.
+// curl: curl -X POST --cookie "sqlite_gui_session=<id>" http://localhost:3000/logout
+func (a *Authenticator) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		a.mu.Lock()
+		delete(a.sessions, cookie.Value)
+		a.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"sqlite-gui/pkg/database"
 	"sqlite-gui/pkg/database/postgresql"
@@ -14,14 +17,27 @@ import (
 )
 
 var (
-	ErrConnectionExists = errors.New("connection already exists")
-	ErrConnectionMiss   = errors.New("connection not found")
+	ErrConnectionExists   = errors.New("connection already exists")
+	ErrConnectionMiss     = errors.New("connection not found")
+	ErrConnectionReadOnly = errors.New("connection is read-only")
+	ErrNotPostgres        = errors.New("connection is not a PostgreSQL connection")
+	ErrInMemoryConnection = errors.New("in-memory connections have no read-only copy (add the connection with ;readonly instead)")
 )
 
+// ConnectionOptions are per-connection settings that are not part of the driver connection string.
+type ConnectionOptions struct {
+	ReadOnly bool
+	Timeout  time.Duration // Longest a /api/query or /api/exec statement may run; 0 for no limit.
+	MaxRows  int           // Most rows /api/query returns; 0 for no limit.
+}
+
 type connectionEntry struct {
 	name       string
 	connString string
+	options    ConnectionOptions
+	base       string // Connection this one was derived from, if any.
 	db         database.Database
+	readOnly   database.Database // Opened by GetReadOnly for connections that allow writes.
 }
 
 type ConnectionManager struct {
@@ -30,10 +46,16 @@ type ConnectionManager struct {
 	defaultName string
 }
 
+// ConnectionInfo describes a connection for /api/connections. ConnString has its password
+// redacted.
 type ConnectionInfo struct {
 	Name       string `json:"name"`
 	ConnString string `json:"connString"`
 	Default    bool   `json:"default"`
+	ReadOnly   bool   `json:"readOnly"`
+	Base       string `json:"base,omitempty"`
+	Timeout    string `json:"timeout,omitempty"`
+	MaxRows    int    `json:"maxRows,omitempty"`
 }
 
 func NewConnectionManager() *ConnectionManager {
@@ -43,17 +65,62 @@ func NewConnectionManager() *ConnectionManager {
 }
 
 func (m *ConnectionManager) Add(ctx context.Context, name, connString string) error {
+	return m.AddWithOptions(ctx, name, connString, ConnectionOptions{})
+}
+
+func (m *ConnectionManager) AddWithOptions(ctx context.Context, name, connString string, opts ConnectionOptions) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.add(ctx, name, connString, opts, "")
+}
+
+// AddDerived opens database dbname on the server of the PostgreSQL connection base, reusing
+// its host, credentials and options. The new connection is named "base.dbname"; deriving
+// from a derived connection uses the original one as base. An already open derived
+// connection is returned as is.
+func (m *ConnectionManager) AddDerived(ctx context.Context, base, dbname string) (ConnectionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.connections[base]
+	if !ok {
+		return ConnectionInfo{}, fmt.Errorf("%w: %s", ErrConnectionMiss, base)
+	}
+	if root := entry.base; root != "" {
+		if entry, ok = m.connections[root]; !ok {
+			return ConnectionInfo{}, fmt.Errorf("%w: %s", ErrConnectionMiss, root)
+		}
+	}
+	if _, ok := entry.db.(*postgresql.Postgres); !ok {
+		return ConnectionInfo{}, fmt.Errorf("%w: %s", ErrNotPostgres, entry.name)
+	}
+	name := entry.name + "." + dbname
+	if existing, ok := m.connections[name]; ok && existing.base == entry.name {
+		return m.info(existing), nil
+	}
+	connString, err := postgresql.WithDatabase(entry.connString, dbname)
+	if err != nil {
+		return ConnectionInfo{}, err
+	}
+	if err := m.add(ctx, name, connString, entry.options, entry.name); err != nil {
+		return ConnectionInfo{}, err
+	}
+	return m.info(m.connections[name]), nil
+}
 
+func (m *ConnectionManager) add(ctx context.Context, name, connString string, opts ConnectionOptions, base string) error {
 	if _, exists := m.connections[name]; exists {
 		return fmt.Errorf("%w: %s", ErrConnectionExists, name)
 	}
 	db := factory(connString)
-	if err := db.Connect(ctx, connString); err != nil {
+	driverConn := connString
+	if opts.ReadOnly {
+		driverConn = readOnlyConnString(connString)
+	}
+	if err := db.Connect(ctx, driverConn); err != nil {
 		return err
 	}
-	m.connections[name] = &connectionEntry{name: name, connString: connString, db: db}
+	m.connections[name] = &connectionEntry{name: name, connString: connString, options: opts, base: base, db: db}
 	if m.defaultName == "" {
 		m.defaultName = name
 	}
@@ -74,6 +141,44 @@ func (m *ConnectionManager) Get(name string) (database.Database, error) {
 	return entry.db, nil
 }
 
+// ReadOnly reports whether the named connection (or the default one when name is empty) is read-only.
+// Unknown connections report false and are left for Get to reject.
+func (m *ConnectionManager) ReadOnly(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name == "" {
+		name = m.defaultName
+	}
+	entry, ok := m.connections[name]
+	return ok && entry.options.ReadOnly
+}
+
+// Options returns the settings of the named connection (or the default one when name is empty).
+func (m *ConnectionManager) Options(name string) ConnectionOptions {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name == "" {
+		name = m.defaultName
+	}
+	if entry, ok := m.connections[name]; ok {
+		return entry.options
+	}
+	return ConnectionOptions{}
+}
+
+// Base returns the connection the named one was derived from, or "" when it was added directly.
+func (m *ConnectionManager) Base(name string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if entry, ok := m.connections[name]; ok {
+		return entry.base
+	}
+	return ""
+}
+
 func (m *ConnectionManager) Default() string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -85,17 +190,72 @@ func (m *ConnectionManager) List() []ConnectionInfo {
 	defer m.mu.RUnlock()
 
 	results := make([]ConnectionInfo, 0, len(m.connections))
-	for name, entry := range m.connections {
-		results = append(results, ConnectionInfo{
-			Name:       name,
-			ConnString: entry.connString,
-			Default:    name == m.defaultName,
-		})
+	for _, entry := range m.connections {
+		results = append(results, m.info(entry))
 	}
 	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
 	return results
 }
 
+// Info describes the named connection.
+func (m *ConnectionManager) Info(name string) (ConnectionInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.connections[name]
+	if !ok {
+		return ConnectionInfo{}, false
+	}
+	return m.info(entry), true
+}
+
+func (m *ConnectionManager) info(entry *connectionEntry) ConnectionInfo {
+	info := ConnectionInfo{
+		Name:       entry.name,
+		ConnString: redactConnString(entry.connString),
+		Default:    entry.name == m.defaultName,
+		ReadOnly:   entry.options.ReadOnly,
+		Base:       entry.base,
+		MaxRows:    entry.options.MaxRows,
+	}
+	if entry.options.Timeout > 0 {
+		info.Timeout = entry.options.Timeout.String()
+	}
+	return info
+}
+
+// GetReadOnly returns a handle on the named connection ("" for the default) that rejects
+// writes. Connections that allow writes get a second, read-only driver connection, opened
+// on first use; in-memory SQLite databases cannot have one and fail with
+// ErrInMemoryConnection.
+func (m *ConnectionManager) GetReadOnly(ctx context.Context, name string) (database.Database, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if name == "" {
+		name = m.defaultName
+	}
+	entry, ok := m.connections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrConnectionMiss, name)
+	}
+	if entry.options.ReadOnly {
+		return entry.db, nil
+	}
+	if entry.readOnly == nil {
+		if !strings.HasPrefix(entry.connString, "postgresql://") && sqlite.InMemory(entry.connString) {
+			// A second connection would open a new, empty database.
+			return nil, fmt.Errorf("%w: %s", ErrInMemoryConnection, name)
+		}
+		db := factory(entry.connString)
+		if err := db.Connect(ctx, readOnlyConnString(entry.connString)); err != nil {
+			return nil, err
+		}
+		entry.readOnly = db
+	}
+	return entry.readOnly, nil
+}
+
 func (m *ConnectionManager) CloseAll() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -105,12 +265,75 @@ func (m *ConnectionManager) CloseAll() error {
 		if err := entry.db.Close(); err != nil && firstErr == nil {
 			firstErr = fmt.Errorf("close %s: %w", name, err)
 		}
+		if entry.readOnly != nil {
+			if err := entry.readOnly.Close(); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("close read-only %s: %w", name, err)
+			}
+		}
 	}
 	m.connections = make(map[string]*connectionEntry)
 	m.defaultName = ""
 	return firstErr
 }
 
+// splitConnectionFlags strips the trailing ";flag" options from a connection argument,
+// e.g. "postgresql://host/db;readonly;timeout=30s;maxrows=10000". Unknown flags end the
+// options and stay part of the connection string; known flags with an invalid value are
+// an error.
+func splitConnectionFlags(raw string) (string, ConnectionOptions, error) {
+	var opts ConnectionOptions
+	for {
+		idx := strings.LastIndex(raw, ";")
+		if idx == -1 {
+			return raw, opts, nil
+		}
+		flag, value, _ := strings.Cut(strings.TrimSpace(raw[idx+1:]), "=")
+		switch strings.ToLower(flag) {
+		case "readonly":
+			opts.ReadOnly = true
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return "", ConnectionOptions{}, fmt.Errorf("invalid timeout %q", value)
+			}
+			opts.Timeout = d
+		case "maxrows":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return "", ConnectionOptions{}, fmt.Errorf("invalid maxrows %q", value)
+			}
+			opts.MaxRows = n
+		default:
+			return raw, opts, nil
+		}
+		raw = strings.TrimSpace(raw[:idx])
+	}
+}
+
+// redactConnString hides the password of a PostgreSQL connection URL, given in its user
+// info or as a password parameter.
+func redactConnString(connString string) string {
+	if !strings.HasPrefix(connString, "postgresql://") {
+		return connString
+	}
+	u, err := url.Parse(connString)
+	if err != nil {
+		return "postgresql://"
+	}
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+func readOnlyConnString(connString string) string {
+	if strings.HasPrefix(connString, "postgresql://") {
+		return postgresql.ReadOnlyConn(connString)
+	}
+	return sqlite.ReadOnlyConn(connString)
+}
+
 func factory(connString string) database.Database {
 	if strings.HasPrefix(connString, "postgresql://") {
 		return postgresql.New()
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+// ErrSessionStatement rejects console statements that control a transaction or the session.
+// Each statement of a script may run on a different pooled connection, so such a statement
+// would not affect the ones after it but could leak into later requests.
+var ErrSessionStatement = errors.New("transaction control and session settings are not supported in the console")
+
+// consoleStep is a statement of a console script, bound and ready to run.
+type consoleStep struct {
+	database.Statement
+	query string
+	args  []any
+}
+
+// consoleFailure reports the statement a console script stopped at.
+func consoleFailure(index int, stmt database.Statement, err error) map[string]any {
+	return map[string]any{
+		"index":  index,
+		"sql":    stmt.SQL,
+		"offset": stmt.Offset,
+		"line":   stmt.Line,
+		"column": stmt.Column,
+		"error":  err.Error(),
+	}
+}
+
+// console runs a script of semicolon-separated statements in order and returns one result
+// per statement: columns and rows for queries, rowsAffected and lastInsertId for other
+// statements, and stats for each. Statements share the :name/@name "params" and each one
+// commits on its own; transaction control (BEGIN, COMMIT, ROLLBACK, SAVEPOINT, ...) and
+// session settings (SET, RESET, ...) are rejected with 400. The script stops at the first
+// error, whose statement is reported under "failed" with its position in the script
+// alongside the results so far. Statements
+// that may change data, the schema or the session (including ones with RETURNING and
+// PRAGMA assignments) need admin access and a writable connection, and are checked before
+// anything runs.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"script":"INSERT INTO users (name) VALUES (:name); SELECT * FROM users WHERE name = :name;","params":{"name":"alice"}}' "http://localhost:3000/api/console?db=db1"
+func (api *API) console(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Script  string         `json:"script"`
+		Params  map[string]any `json:"params"`
+		Timeout string         `json:"timeout"`
+		MaxRows int            `json:"maxRows"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	statements := database.SplitStatements(req.Script)
+	if len(statements) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("script has no statements"))
+		return
+	}
+	limits, err := api.limitsFor(r, req.Timeout, req.MaxRows)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := api.connectionName(r)
+	steps := make([]consoleStep, len(statements))
+	for i, stmt := range statements {
+		step := consoleStep{Statement: stmt, query: stmt.SQL}
+		if req.Params != nil {
+			if step.query, step.args, err = database.BindNamed(stmt.SQL, req.Params, db.Placeholder); err != nil {
+				writeConsoleError(w, http.StatusBadRequest, nil, consoleFailure(i, stmt, err))
+				return
+			}
+		}
+		if stmt.ControlsSession() {
+			err := fmt.Errorf("%w: %s", ErrSessionStatement, stmt.SQL)
+			writeConsoleError(w, http.StatusBadRequest, nil, consoleFailure(i, stmt, err))
+			return
+		}
+		if stmt.Writes() {
+			if !api.canWrite(r) {
+				err := fmt.Errorf("%w: %s access to %s required", ErrForbidden, RoleAdmin, name)
+				writeConsoleError(w, http.StatusForbidden, nil, consoleFailure(i, stmt, err))
+				return
+			}
+			if api.connections.ReadOnly(name) {
+				err := fmt.Errorf("%w: %s", ErrConnectionReadOnly, name)
+				writeConsoleError(w, http.StatusForbidden, nil, consoleFailure(i, stmt, err))
+				return
+			}
+		}
+		steps[i] = step
+	}
+
+	start := time.Now()
+	results := make([]map[string]any, 0, len(steps))
+	for i, step := range steps {
+		result := map[string]any{
+			"index":  i,
+			"sql":    step.SQL,
+			"line":   step.Line,
+			"column": step.Column,
+		}
+		if step.ReturnsRows() {
+			res, err := api.queryStatement(r, db, step.query, step.args, limits)
+			if err != nil {
+				writeConsoleError(w, statementStatus(err), results, consoleFailure(i, step.Statement, err))
+				return
+			}
+			result["kind"] = "query"
+			result["queryId"] = res.ID
+			result["columns"] = res.Columns
+			result["rows"] = json.RawMessage(res.Encoded)
+			result["truncated"] = res.Truncated
+			result["stats"] = res.Stats
+		} else {
+			res, err := api.execStatement(r, db, step.query, step.args, limits)
+			if err != nil {
+				writeConsoleError(w, statementStatus(err), results, consoleFailure(i, step.Statement, err))
+				return
+			}
+			result["kind"] = "exec"
+			result["queryId"] = res.ID
+			result["rowsAffected"] = res.RowsAffected
+			result["lastInsertId"] = res.LastInsertID
+			result["stats"] = res.Stats
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"results":    results,
+		"durationMs": milliseconds(time.Since(start)),
+	})
+}
+
+// writeConsoleError answers with the results of the statements that ran before failed.
+func writeConsoleError(w http.ResponseWriter, status int, results []map[string]any, failed map[string]any) {
+	if results == nil {
+		results = []map[string]any{}
+	}
+	writeJSON(w, status, map[string]any{
+		"error":   failed["error"],
+		"failed":  failed,
+		"results": results,
+	})
+}
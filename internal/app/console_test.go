@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConsoleRunsScript(t *testing.T) {
+	api := newTestAPI(t)
+
+	code, resp := api.runConsole(`{"script":"CREATE TABLE t (id INTEGER PRIMARY KEY, note TEXT);\nINSERT INTO t (note) VALUES (:note), ('b;c');\nSELECT note, id FROM t ORDER BY id;","params":{"note":"a"}}`)
+	if code != http.StatusOK || len(resp.Results) != 3 {
+		t.Fatalf("run: %d %+v", code, resp)
+	}
+	insert, query := resp.Results[1], resp.Results[2]
+	if insert.Kind != "exec" || insert.RowsAffected != 2 || insert.LastInsertID != 2 || insert.Line != 2 {
+		t.Fatalf("unexpected insert result %+v", insert)
+	}
+	if query.Kind != "query" || strings.Join(query.Columns, ",") != "note,id" || len(query.Rows) != 2 || query.Rows[1]["note"] != "b;c" || query.Stats.RowCount != 2 {
+		t.Fatalf("unexpected query result %+v", query)
+	}
+
+	code, resp = api.runConsole(`{"script":"SELECT 1 AS one;\n  SELECT * FROM missing;\nDELETE FROM t;"}`)
+	if code != http.StatusBadRequest || len(resp.Results) != 1 || resp.Failed.Index != 1 || resp.Failed.Line != 2 || resp.Failed.Column != 3 || resp.Error == "" {
+		t.Fatalf("expected the script to stop at line 2, got %d %+v", code, resp)
+	}
+	if n, _ := api.db.Count(context.Background(), "t"); n != 2 {
+		t.Fatalf("expected the statements after the error to be skipped, %d rows left", n)
+	}
+}
+
+func TestConsoleRejectsTransactionControl(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec("CREATE TABLE t (id INTEGER PRIMARY KEY)", "INSERT INTO t VALUES (1), (2)")
+
+	// Run statement by statement on pooled connections, the DELETE would commit on its own.
+	code, resp := api.runConsole(`{"script":"BEGIN;\nDELETE FROM t;\nROLLBACK;"}`)
+	if code != http.StatusBadRequest || len(resp.Results) != 0 || resp.Failed.Index != 0 {
+		t.Fatalf("expected BEGIN to be rejected before anything runs, got %d %+v", code, resp)
+	}
+	if n, _ := api.db.Count(context.Background(), "t"); n != 2 {
+		t.Fatalf("expected the rolled-back delete not to persist, %d rows left", n)
+	}
+	if code, resp := api.runConsole(`{"script":"SELECT 1;\nSET search_path TO other;"}`); code != http.StatusBadRequest || resp.Failed.Index != 1 {
+		t.Fatalf("expected SET to be rejected, got %d %+v", code, resp)
+	}
+}
+
+// runConsole posts a script to /api/console and decodes the response.
+func (a *testAPI) runConsole(body string) (int, consoleResponse) {
+	a.t.Helper()
+	rec := a.do(http.MethodPost, "/api/console", body)
+	var resp consoleResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		a.t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return rec.Code, resp
+}
+
+type consoleResponse struct {
+	Results []struct {
+		Kind         string           `json:"kind"`
+		Line         int              `json:"line"`
+		Columns      []string         `json:"columns"`
+		Rows         []map[string]any `json:"rows"`
+		RowsAffected int64            `json:"rowsAffected"`
+		LastInsertID int64            `json:"lastInsertId"`
+		Stats        statementStats   `json:"stats"`
+	} `json:"results"`
+	Error  string `json:"error"`
+	Failed struct {
+		Index  int `json:"index"`
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"failed"`
+}
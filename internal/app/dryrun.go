@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+)
+
+// warnFunc adds data loss and dependency warnings for a previewed schema change.
+type warnFunc func(ctx context.Context, plan *database.Plan) error
+
+// isDryRun reports whether a schema-changing request asked for a preview via ?dryRun=true.
+func isDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dryRun") == "true"
+}
+
+// preview runs change in dry-run mode and responds with the statements it would execute
+// plus any warnings, without touching the database.
+func preview(w http.ResponseWriter, r *http.Request, change func(ctx context.Context) error, warn warnFunc) {
+	plan := &database.Plan{Statements: []string{}, Warnings: []string{}}
+	if err := change(database.WithPlan(r.Context(), plan)); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if warn != nil {
+		if err := warn(r.Context(), plan); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"dryRun":     true,
+		"statements": plan.Statements,
+		"warnings":   plan.Warnings,
+	})
+}
+
+// dependentWarnings warns about views and triggers referencing table (or column).
+func dependentWarnings(db database.Database, table, column string) warnFunc {
+	return func(ctx context.Context, plan *database.Plan) error {
+		deps, err := db.Dependents(ctx, table, column)
+		if err != nil {
+			return err
+		}
+		target := table
+		if column != "" {
+			target = table + "." + column
+		}
+		for _, dep := range deps {
+			plan.Warn("%s %s references %s and may break", dep.Type, dep.Name, target)
+		}
+		return nil
+	}
+}
+
+// rowLossWarning adds message when table holds data, along with its row count.
+// Tables that cannot be counted (for example because they do not exist) are skipped.
+func rowLossWarning(ctx context.Context, db database.Database, plan *database.Plan, table, message string) {
+	count, err := db.Count(ctx, table)
+	if err != nil || count == 0 {
+		return
+	}
+	plan.Warn("%s (%d rows)", message, count)
+}
+
+func dropTableWarnings(db database.Database, table string) warnFunc {
+	return func(ctx context.Context, plan *database.Plan) error {
+		rowLossWarning(ctx, db, plan, table, "dropping "+table+" deletes all of its data")
+		return dependentWarnings(db, table, "")(ctx, plan)
+	}
+}
+
+func dropColumnWarnings(db database.Database, table, column string) warnFunc {
+	return func(ctx context.Context, plan *database.Plan) error {
+		rowLossWarning(ctx, db, plan, table, "dropping "+table+"."+column+" discards its values")
+		return dependentWarnings(db, table, column)(ctx, plan)
+	}
+}
+
+func addColumnWarnings(db database.Database, table string, column database.ColumnDef) warnFunc {
+	return func(ctx context.Context, plan *database.Plan) error {
+		if column.NotNull && column.Default == nil {
+			rowLossWarning(ctx, db, plan, table, "NOT NULL column "+column.Name+" has no default but the table is not empty")
+		}
+		return nil
+	}
+}
+
+func alterColumnWarnings(db database.Database, table, column string, def database.ColumnDef) warnFunc {
+	return func(ctx context.Context, plan *database.Plan) error {
+		cols, err := db.Columns(ctx, table)
+		if err != nil {
+			return err
+		}
+		for _, cur := range cols {
+			if cur.Name == column {
+				columnChangeWarnings(ctx, db, plan, table, cur, def)
+			}
+		}
+		return dependentWarnings(db, table, column)(ctx, plan)
+	}
+}
+
+func alterTableWarnings(db database.Database, table string, columns []database.ColumnDef) warnFunc {
+	return func(ctx context.Context, plan *database.Plan) error {
+		cols, err := db.Columns(ctx, table)
+		if err != nil {
+			return err
+		}
+		wanted := make(map[string]database.ColumnDef, len(columns))
+		for _, col := range columns {
+			wanted[col.Name] = col
+		}
+		for _, cur := range cols {
+			def, ok := wanted[cur.Name]
+			if !ok {
+				rowLossWarning(ctx, db, plan, table, "dropping "+table+"."+cur.Name+" discards its values")
+				if err := dependentWarnings(db, table, cur.Name)(ctx, plan); err != nil {
+					return err
+				}
+				continue
+			}
+			columnChangeWarnings(ctx, db, plan, table, cur, def)
+		}
+		return nil
+	}
+}
+
+// columnChangeWarnings flags type and nullability changes that can rewrite or reject existing values.
+func columnChangeWarnings(ctx context.Context, db database.Database, plan *database.Plan, table string, cur database.Column, def database.ColumnDef) {
+	if def.Type != "" && !strings.EqualFold(cur.Type, def.Type) {
+		rowLossWarning(ctx, db, plan, table, "changing "+cur.Name+" from "+cur.Type+" to "+def.Type+" may convert or reject existing values")
+	}
+	if def.NotNull && !cur.NotNull {
+		rowLossWarning(ctx, db, plan, table, "making "+cur.Name+" NOT NULL fails if any existing value is NULL")
+	}
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestDropTableDryRun(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec("CREATE TABLE users (id INTEGER PRIMARY KEY)", "INSERT INTO users (id) VALUES (1), (2)")
+
+	rec := api.do(http.MethodDelete, "/api/tables/users?dryRun=true", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Statements []string `json:"statements"`
+		Warnings   []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Statements) != 1 || resp.Statements[0] != `DROP TABLE IF EXISTS "users"` {
+		t.Fatalf("unexpected statements %v", resp.Statements)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("expected a data loss warning, got %v", resp.Warnings)
+	}
+	if total, err := api.db.Count(context.Background(), "users"); err != nil || total != 2 {
+		t.Fatalf("dry run should keep the table, count=%d err=%v", total, err)
+	}
+}
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+const (
+	eventBuffer       = 64 // Events queued per subscriber before new ones are dropped.
+	eventKeepAlive    = 15 * time.Second
+	watchRetryBackoff = 5 * time.Second
+)
+
+// ChangeEvent describes a change to a connection. Source is "api" for changes made through
+// this server and "database" for changes detected in the database itself.
+type ChangeEvent struct {
+	Connection string       `json:"connection"`
+	Schema     string       `json:"schema,omitempty"`
+	Table      string       `json:"table,omitempty"` // Empty when unknown, e.g. for /api/exec.
+	Action     string       `json:"action"`
+	Key        database.Key `json:"key,omitempty"`
+	User       string       `json:"user,omitempty"`
+	Source     string       `json:"source"`
+	Time       time.Time    `json:"time"`
+}
+
+type subscriber struct {
+	connection string
+	table      string // Empty to receive every table.
+	events     chan ChangeEvent
+}
+
+func (s *subscriber) wants(ev ChangeEvent) bool {
+	return s.table == "" || ev.Table == "" || ev.Table == s.table
+}
+
+// EventHub fans change events out to subscribers. While a connection has subscribers, a
+// watcher reports changes made by other clients of its database.
+type EventHub struct {
+	connections *ConnectionManager
+
+	mu       sync.Mutex
+	subs     map[string]map[*subscriber]struct{}
+	watchers map[string]context.CancelFunc
+}
+
+func NewEventHub(connections *ConnectionManager) *EventHub {
+	return &EventHub{
+		connections: connections,
+		subs:        make(map[string]map[*subscriber]struct{}),
+		watchers:    make(map[string]context.CancelFunc),
+	}
+}
+
+func (h *EventHub) Subscribe(connection, table string) *subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &subscriber{connection: connection, table: table, events: make(chan ChangeEvent, eventBuffer)}
+	if h.subs[connection] == nil {
+		h.subs[connection] = make(map[*subscriber]struct{})
+	}
+	h.subs[connection][sub] = struct{}{}
+	if _, ok := h.watchers[connection]; !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.watchers[connection] = cancel
+		go h.watch(ctx, connection)
+	}
+	return sub
+}
+
+func (h *EventHub) Unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs[sub.connection], sub)
+	if len(h.subs[sub.connection]) == 0 {
+		delete(h.subs, sub.connection)
+		if cancel, ok := h.watchers[sub.connection]; ok {
+			cancel()
+			delete(h.watchers, sub.connection)
+		}
+	}
+}
+
+// Publish delivers ev to the connection's subscribers. Slow subscribers miss events rather
+// than blocking the publisher.
+func (h *EventHub) Publish(ev ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs[ev.Connection] {
+		if !sub.wants(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+		}
+	}
+}
+
+// watch runs the driver's change watcher for connection until ctx is done, restarting it
+// after errors.
+func (h *EventHub) watch(ctx context.Context, connection string) {
+	for {
+		db, err := h.connections.Get(connection)
+		if err != nil {
+			return
+		}
+		err = db.WatchChanges(ctx, func(schema, table string) {
+			h.Publish(ChangeEvent{Connection: connection, Schema: schema, Table: table, Action: "change", Source: "database", Time: time.Now()})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("events: watching %s failed: %v", connection, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryBackoff):
+		}
+	}
+}
+
+// getEvents streams change events for a connection as Server-Sent Events, optionally
+// limited to one table name (in any schema). Each event is sent as "event: change" with a
+// JSON ChangeEvent.
+// curl: curl -N "http://localhost:3000/api/events?db=db1&table=users"
+func (api *API) getEvents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := api.useDB(w, r); !ok {
+		return
+	}
+	sub := api.events.Subscribe(api.connectionName(r), r.URL.Query().Get("table"))
+	defer api.events.Unsubscribe(sub)
+	streamEvents(w, r, "change", sub.events)
+}
+
+// streamEvents writes events to w as Server-Sent Events named name until the client goes
+// away or events is closed.
+func streamEvents[T any](w http.ResponseWriter, r *http.Request, name string, events <-chan T) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// installChangeTrigger adds a Postgres trigger that reports writes to table from any
+// client to /api/events subscribers.
+// curl: curl -X POST "http://localhost:3000/api/events/triggers/users?db=pg"
+func (api *API) installChangeTrigger(w http.ResponseWriter, r *http.Request) {
+	api.changeTrigger(w, r, true)
+}
+
+// removeChangeTrigger drops the trigger added by installChangeTrigger.
+// curl: curl -X DELETE "http://localhost:3000/api/events/triggers/users?db=pg"
+func (api *API) removeChangeTrigger(w http.ResponseWriter, r *http.Request) {
+	api.changeTrigger(w, r, false)
+}
+
+type changeTriggerInstaller interface {
+	InstallChangeTrigger(ctx context.Context, table string) error
+	RemoveChangeTrigger(ctx context.Context, table string) error
+}
+
+func (api *API) changeTrigger(w http.ResponseWriter, r *http.Request, install bool) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	installer, ok := db.(changeTriggerInstaller)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("change triggers are only supported on PostgreSQL; SQLite changes are detected automatically"))
+		return
+	}
+	table := r.PathValue("table")
+	var err error
+	if install {
+		err = installer.InstallChangeTrigger(r.Context(), table)
+	} else {
+		err = installer.RemoveChangeTrigger(r.Context(), table)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
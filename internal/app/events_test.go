@@ -0,0 +1,60 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsStreamAPIChanges(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec(
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE teams (id INTEGER PRIMARY KEY, name TEXT)",
+	)
+	srv := httptest.NewServer(api.mux)
+	defer srv.Close()
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/api/events?table=users", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	for _, table := range []string{"teams", "users"} {
+		post, err := http.Post(srv.URL+"/api/tables/"+table+"/rows", "application/json", strings.NewReader(`{"name":"x"}`))
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		post.Body.Close()
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev ChangeEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		// The teams insert is filtered out, so the first event is the users insert.
+		if ev.Table != "users" || ev.Action != "insert" || ev.Source != "api" || ev.Connection != "main" {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+		return
+	}
+	t.Fatalf("stream ended without an event: %v", scanner.Err())
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"sqlite-gui/pkg/database"
+)
+
+// explain returns the plan of a query as a tree of nodes with their type, relation, index,
+// estimated and actual rows and cost. "analyze" runs the query to measure actual rows (on
+// PostgreSQL, inside a transaction that is rolled back); "buffers" adds buffer usage.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"query":"SELECT * FROM users WHERE email = ?","args":["a@b.c"],"analyze":true}' "http://localhost:3000/api/explain?db=db1"
+func (api *API) explain(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Query string `json:"query"`
+		Args  []any  `json:"args"`
+		database.ExplainOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := api.limitsFor(r, "", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, running, finish := api.trackQuery(r, "explain", req.Query)
+	ctx, _, cancel := withLimits(ctx, limits)
+	defer cancel()
+	plan, err := db.Explain(ctx, req.Query, req.ExplainOptions, req.Args...)
+	if cancelled := finish(); cancelled && err != nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("%w: %s", ErrQueryCancelled, running.ID))
+		return
+	}
+	if err != nil {
+		writeStatementError(w, ctx, err, limits)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"plan": plan})
+}
@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+)
+
+const (
+	cardinalityManyToOne = "many-to-one"
+	cardinalityOneToOne  = "one-to-one"
+)
+
+type graphColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	NotNull    bool   `json:"notNull"`
+	PrimaryKey bool   `json:"primaryKey"`
+	ForeignKey bool   `json:"foreignKey"`
+}
+
+type graphNode struct {
+	Table   string        `json:"table"`
+	Columns []graphColumn `json:"columns"`
+}
+
+// graphEdge points from the referencing (child) table to the referenced (parent) table.
+type graphEdge struct {
+	foreignKey
+	Cardinality string `json:"cardinality"`
+	Optional    bool   `json:"optional"` // Some referencing column is nullable, so a child may have no parent.
+}
+
+type schemaGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// getSchemaGraph exports the table/foreign key graph as JSON, Graphviz DOT or a Mermaid ER diagram.
+// curl: curl -X GET "http://localhost:3000/api/schema/graph?format=mermaid&db=db1"
+func (api *API) getSchemaGraph(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "dot" && format != "mermaid" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %q (want json, dot or mermaid)", format))
+		return
+	}
+	graph, err := buildSchemaGraph(r.Context(), db)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch format {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Write([]byte(graph.dot()))
+	case "mermaid":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(graph.mermaid()))
+	default:
+		writeJSON(w, http.StatusOK, graph)
+	}
+}
+
+func buildSchemaGraph(ctx context.Context, db database.Database) (*schemaGraph, error) {
+	tables, err := db.Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graph := &schemaGraph{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	for _, table := range tables {
+		cols, err := db.Columns(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		node := graphNode{Table: table}
+		notNull := make(map[string]bool, len(cols))
+		var pk []string
+		for _, col := range cols {
+			node.Columns = append(node.Columns, graphColumn{
+				Name:       col.Name,
+				Type:       col.Type,
+				NotNull:    col.NotNull,
+				PrimaryKey: col.PrimaryKey,
+				ForeignKey: len(col.ForeignKeys) > 0,
+			})
+			notNull[col.Name] = col.NotNull || col.PrimaryKey
+			if col.PrimaryKey {
+				pk = append(pk, col.Name)
+			}
+		}
+		graph.Nodes = append(graph.Nodes, node)
+
+		for _, fk := range groupForeignKeys(table, cols) {
+			if err := resolveRefColumns(ctx, db, &fk); err != nil {
+				return nil, err
+			}
+			edge := graphEdge{foreignKey: fk, Cardinality: cardinalityManyToOne}
+			// A foreign key that is also the whole primary key allows at most one child per parent.
+			if sameColumnSet(fk.Columns, pk) {
+				edge.Cardinality = cardinalityOneToOne
+			}
+			for _, col := range fk.Columns {
+				if !notNull[col] {
+					edge.Optional = true
+				}
+			}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	return graph, nil
+}
+
+func (g *schemaGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph schema {\n\trankdir=LR;\n\tnode [shape=record];\n")
+	for _, node := range g.Nodes {
+		fields := make([]string, len(node.Columns))
+		for i, col := range node.Columns {
+			field := col.Name + " : " + col.Type
+			if col.PrimaryKey {
+				field += " (PK)"
+			}
+			fields[i] = dotRecordEscape(field) + `\l`
+		}
+		fmt.Fprintf(&b, "\t%s [label=\"{%s|%s}\"];\n", dotQuote(node.Table), dotRecordEscape(node.Table), strings.Join(fields, ""))
+	}
+	for _, edge := range g.Edges {
+		tail := "N"
+		if edge.Cardinality == cardinalityOneToOne {
+			tail = "1"
+		}
+		head := "1"
+		if edge.Optional {
+			head = "0..1"
+		}
+		label := strings.Join(edge.Columns, ", ") + " → " + strings.Join(edge.RefColumns, ", ")
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s, taillabel=%s, headlabel=%s];\n",
+			dotQuote(edge.Table), dotQuote(edge.refName()), dotQuote(label), dotQuote(tail), dotQuote(head))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *schemaGraph) mermaid() string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "    %s {\n", mermaidIdent(node.Table))
+		for _, col := range node.Columns {
+			typ := mermaidIdent(col.Type)
+			if typ == "" {
+				typ = "ANY"
+			}
+			var keys []string
+			if col.PrimaryKey {
+				keys = append(keys, "PK")
+			}
+			if col.ForeignKey {
+				keys = append(keys, "FK")
+			}
+			line := fmt.Sprintf("        %s %s", typ, mermaidIdent(col.Name))
+			if len(keys) > 0 {
+				line += " " + strings.Join(keys, ", ")
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, edge := range g.Edges {
+		child := "}o"
+		if edge.Cardinality == cardinalityOneToOne {
+			child = "|o"
+		}
+		parent := "||"
+		if edge.Optional {
+			parent = "o|"
+		}
+		label := strings.ReplaceAll(strings.Join(edge.Columns, ", "), `"`, `'`)
+		fmt.Fprintf(&b, "    %s %s--%s %s : \"%s\"\n", mermaidIdent(edge.Table), child, parent, mermaidIdent(edge.refName()), label)
+	}
+	return b.String()
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_\-()\[\],]+`)
+
+// mermaidIdent replaces characters Mermaid does not accept in entity, type and attribute names.
+func mermaidIdent(name string) string {
+	return mermaidUnsafe.ReplaceAllString(strings.TrimSpace(name), "_")
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+// dotRecordEscape escapes the characters that are structural inside a DOT record label.
+func dotRecordEscape(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`)
+	return replacer.Replace(s)
+}
+
+func sameColumnSet(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, c := range a {
+		set[c] = true
+	}
+	for _, c := range b {
+		if !set[c] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"sqlite-gui/pkg/database"
+)
+
+func TestSchemaGraphCompositeForeignKey(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec(`
+		CREATE TABLE teams (org_id INTEGER, id INTEGER, name TEXT, PRIMARY KEY (org_id, id));
+		CREATE TABLE members (id INTEGER PRIMARY KEY, org_id INTEGER NOT NULL, team_id INTEGER NOT NULL,
+			FOREIGN KEY (org_id, team_id) REFERENCES teams (org_id, id));
+		CREATE TABLE profiles (member_id INTEGER PRIMARY KEY REFERENCES members);
+	`)
+
+	graph, err := buildSchemaGraph(context.Background(), api.db)
+	if err != nil {
+		t.Fatalf("graph: %v", err)
+	}
+	if len(graph.Nodes) != 3 || len(graph.Edges) != 2 {
+		t.Fatalf("unexpected graph %+v", graph)
+	}
+	for _, edge := range graph.Edges {
+		switch edge.Table {
+		case "members":
+			if len(edge.Columns) != 2 || edge.RefColumns[1] != "id" || edge.Cardinality != cardinalityManyToOne || edge.Optional {
+				t.Fatalf("unexpected members edge %+v", edge)
+			}
+		case "profiles":
+			if edge.RefColumns[0] != "id" || edge.Cardinality != cardinalityOneToOne {
+				t.Fatalf("unexpected profiles edge %+v", edge)
+			}
+		}
+	}
+
+	mermaid := graph.mermaid()
+	if !strings.Contains(mermaid, `members }o--|| teams : "org_id, team_id"`) {
+		t.Fatalf("missing composite relation in mermaid output:\n%s", mermaid)
+	}
+	if !strings.Contains(graph.dot(), `"members" -> "teams"`) {
+		t.Fatalf("missing composite relation in dot output:\n%s", graph.dot())
+	}
+}
+
+func TestSchemaGraphQualifiesOtherSchemas(t *testing.T) {
+	cols := []database.Column{{Name: "tenant_id", ForeignKeys: []database.ForeignKey{
+		{Constraint: "orders_tenant_fk", FromCol: "tenant_id", RefSchema: "admin", RefTable: "tenants", ToCol: "id"},
+	}}}
+	fks := groupForeignKeys("orders", cols)
+	if len(fks) != 1 || fks[0].RefSchema != "admin" {
+		t.Fatalf("unexpected foreign keys %+v", fks)
+	}
+	graph := &schemaGraph{Edges: []graphEdge{{foreignKey: fks[0], Cardinality: cardinalityManyToOne}}}
+	if !strings.Contains(graph.dot(), `"orders" -> "admin.tenants"`) {
+		t.Fatalf("missing qualified relation in dot output:\n%s", graph.dot())
+	}
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+const defaultHistoryLimit = 50
+
+var ErrHistoryEntryNotFound = errors.New("history entry not found")
+
+// HistoryEntry is one /api/query or /api/exec call.
+type HistoryEntry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Connection string    `json:"connection"`
+	Kind       string    `json:"kind"`
+	User       string    `json:"user,omitempty"`
+	Statement  string    `json:"statement"`
+	Args       []any     `json:"args,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+	RowCount   int64     `json:"rowCount"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// HistoryFilter narrows ListHistory results. Zero values match everything; Search is
+// matched word by word, as prefixes, against the statement and error text.
+type HistoryFilter struct {
+	User       string
+	Connection string
+	Kind       string
+	Search     string
+	Limit      int
+	Offset     int
+}
+
+func (m *Metadata) RecordHistory(ctx context.Context, entry HistoryEntry) error {
+	args, err := encodeAuditValue(entry.Args)
+	if err != nil {
+		return fmt.Errorf("encode args: %w", err)
+	}
+	return m.db.Insert(ctx, "history", database.Row{
+		"time":        entry.Time.UTC().Format(auditTimeFormat),
+		"connection":  entry.Connection,
+		"kind":        entry.Kind,
+		"user":        nullString(entry.User),
+		"statement":   entry.Statement,
+		"args":        args,
+		"duration_ms": entry.DurationMs,
+		"row_count":   entry.RowCount,
+		"error":       nullString(entry.Error),
+	})
+}
+
+// ListHistory returns one page of matching entries, newest first, and the number of
+// matching entries in total.
+func (m *Metadata) ListHistory(ctx context.Context, filter HistoryFilter) ([]HistoryEntry, int64, error) {
+	where, args := historyWhere(filter)
+	if match := ftsQuery(filter.Search); match != "" {
+		where = append(where, "id IN (SELECT rowid FROM history_fts WHERE history_fts MATCH ?)")
+		args = append(args, match)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	counted, err := m.db.Query(ctx, "SELECT COUNT(*) AS n FROM history"+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, _ := counted[0]["n"].(int64)
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	rows, err := m.db.Query(ctx, "SELECT * FROM history"+clause+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]HistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		entry, err := decodeHistoryRow(row)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, nil
+}
+
+// DeleteHistory removes entry id, provided it belongs to user ("" for any user).
+func (m *Metadata) DeleteHistory(ctx context.Context, user string, id int64) error {
+	where, args := historyWhere(HistoryFilter{User: user})
+	where = append(where, "id = ?")
+	res, err := m.db.Exec(ctx, "DELETE FROM history WHERE "+strings.Join(where, " AND "), append(args, id)...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrHistoryEntryNotFound
+	}
+	return nil
+}
+
+// ClearHistory removes the entries matching filter's user, connection and kind, and
+// reports how many were removed.
+func (m *Metadata) ClearHistory(ctx context.Context, filter HistoryFilter) (int64, error) {
+	where, args := historyWhere(filter)
+	query := "DELETE FROM history"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	res, err := m.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func historyWhere(filter HistoryFilter) (where []string, args []any) {
+	for _, cond := range []struct{ column, value string }{
+		{"user", filter.User},
+		{"connection", filter.Connection},
+		{"kind", filter.Kind},
+	} {
+		if cond.value != "" {
+			where = append(where, cond.column+" = ?")
+			args = append(args, cond.value)
+		}
+	}
+	return where, args
+}
+
+// ftsQuery turns free text into an FTS5 query matching every word as a prefix. Words are
+// quoted so operators and punctuation in SQL do not reach the FTS5 query parser.
+func ftsQuery(search string) string {
+	var terms []string
+	for _, word := range strings.Fields(search) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+func decodeHistoryRow(row database.Row) (HistoryEntry, error) {
+	text := func(column string) string {
+		s, _ := row[column].(string)
+		return s
+	}
+	entry := HistoryEntry{
+		Connection: text("connection"),
+		Kind:       text("kind"),
+		User:       text("user"),
+		Statement:  text("statement"),
+		Error:      text("error"),
+	}
+	entry.ID, _ = row["id"].(int64)
+	entry.DurationMs, _ = row["duration_ms"].(float64)
+	entry.RowCount, _ = row["row_count"].(int64)
+	t, err := time.Parse(auditTimeFormat, text("time"))
+	if err != nil {
+		return entry, err
+	}
+	entry.Time = t
+	if raw := text("args"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &entry.Args); err != nil {
+			return entry, fmt.Errorf("decode args of history entry %d: %w", entry.ID, err)
+		}
+	}
+	return entry, nil
+}
+
+// recordHistory adds a finished statement to the query history. Failures are logged,
+// since the statement itself has already run.
+func (api *API) recordHistory(r *http.Request, entry HistoryEntry) {
+	if api.metadata == nil {
+		return
+	}
+	if err := api.metadata.RecordHistory(r.Context(), entry); err != nil {
+		log.Printf("history: failed to record %s on %s: %v", entry.Kind, entry.Connection, err)
+	}
+}
+
+// historyFilter reads the db, kind, q, limit and offset parameters of r. Users only ever
+// see their own history.
+func historyFilter(r *http.Request) HistoryFilter {
+	q := r.URL.Query()
+	return HistoryFilter{
+		User:       Principal(r.Context()),
+		Connection: q.Get("db"),
+		Kind:       q.Get("kind"),
+		Search:     q.Get("q"),
+		Limit:      queryInt(r, "limit"),
+		Offset:     queryInt(r, "offset"),
+	}
+}
+
+// getHistory lists the caller's /api/query and /api/exec calls, newest first. q searches
+// the statement and error text; db and kind (query or exec) narrow the results.
+// curl: curl -X GET "http://localhost:3000/api/history?q=select+users&db=main&limit=20&offset=0"
+func (api *API) getHistory(w http.ResponseWriter, r *http.Request) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return
+	}
+	entries, total, err := api.metadata.ListHistory(r.Context(), historyFilter(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": entries, "total": total})
+}
+
+// deleteHistoryEntry removes one of the caller's history entries.
+// curl: curl -X DELETE "http://localhost:3000/api/history/42"
+func (api *API) deleteHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid history id: %w", err))
+		return
+	}
+	err = api.metadata.DeleteHistory(r.Context(), Principal(r.Context()), id)
+	if errors.Is(err, ErrHistoryEntryNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// clearHistory removes the caller's history, optionally only for one connection (?db=)
+// or kind (?kind=).
+// curl: curl -X DELETE "http://localhost:3000/api/history?db=main"
+func (api *API) clearHistory(w http.ResponseWriter, r *http.Request) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return
+	}
+	filter := historyFilter(r)
+	filter.Search = ""
+	removed, err := api.metadata.ClearHistory(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "removed": removed})
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQueryHistory(t *testing.T) {
+	api := newTestAPI(t)
+	api.useMetadata()
+	api.do(http.MethodPost, "/api/exec", `{"query":"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"}`)
+	api.do(http.MethodPost, "/api/exec", `{"query":"INSERT INTO users (name) VALUES (?), (?)","args":["alice","bob"]}`)
+	api.do(http.MethodPost, "/api/query", `{"query":"SELECT name FROM users WHERE name = ?","args":["alice"]}`)
+	api.do(http.MethodPost, "/api/query", `{"query":"SELECT * FROM missing"}`)
+
+	list := func(params string) (entries []HistoryEntry, total int64) {
+		rec := api.do(http.MethodGet, "/api/history?"+params, "")
+		var resp struct {
+			Entries []HistoryEntry `json:"entries"`
+			Total   int64          `json:"total"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+		return resp.Entries, resp.Total
+	}
+
+	entries, total := list("limit=2")
+	if total != 4 || len(entries) != 2 || entries[0].Statement != "SELECT * FROM missing" || entries[0].Error == "" {
+		t.Fatalf("unexpected first page (total %d): %+v", total, entries)
+	}
+	if entries[1].RowCount != 1 || entries[1].Kind != "query" || entries[1].Connection != "main" || fmt.Sprint(entries[1].Args) != "[alice]" {
+		t.Fatalf("unexpected query entry: %+v", entries[1])
+	}
+	if entries, total := list("q=insert+user&kind=exec"); total != 1 || entries[0].RowCount != 2 {
+		t.Fatalf("expected the insert to match, got %d: %+v", total, entries)
+	}
+	if _, total := list(`q="(*`); total != 0 {
+		t.Fatalf("expected no match for punctuation, got %d", total)
+	}
+
+	if rec := api.do(http.MethodDelete, fmt.Sprintf("/api/history/%d", entries[0].ID), ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodDelete, fmt.Sprintf("/api/history/%d", entries[0].ID), ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting twice, got %d", rec.Code)
+	}
+	if _, total := list("q=missing"); total != 0 {
+		t.Fatalf("expected the deleted entry to leave the search index")
+	}
+	if rec := api.do(http.MethodDelete, "/api/history?db=main", ""); !strings.Contains(rec.Body.String(), `"removed":3`) {
+		t.Fatalf("clear: %d %s", rec.Code, rec.Body)
+	}
+	if _, total := list(""); total != 0 {
+		t.Fatalf("expected an empty history, got %d entries", total)
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+var ErrStatementTimeout = errors.New("statement timeout")
+
+// statementLimits bound one /api/query or /api/exec statement.
+type statementLimits struct {
+	Timeout time.Duration
+	MaxRows int
+}
+
+// limitsFor returns the connection's limits with the request's overrides applied. timeout
+// is a Go duration such as "5s"; zero values keep the connection's setting. Admins of the
+// connection may raise or lift a limit (with a negative maxRows or "0s"); other users may
+// only tighten it.
+func (api *API) limitsFor(r *http.Request, timeout string, maxRows int) (statementLimits, error) {
+	name := api.connectionName(r)
+	opts := api.connections.Options(name)
+	limits := statementLimits{Timeout: opts.Timeout, MaxRows: opts.MaxRows}
+	admin := api.access == nil || api.roleOn(Principal(r.Context()), name).Allows(RoleAdmin)
+
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d < 0 {
+			return limits, fmt.Errorf("invalid timeout %q", timeout)
+		}
+		if admin || (d > 0 && (limits.Timeout == 0 || d < limits.Timeout)) {
+			limits.Timeout = d
+		}
+	}
+	if maxRows < 0 && admin {
+		limits.MaxRows = 0
+	} else if maxRows > 0 && (admin || limits.MaxRows == 0 || maxRows < limits.MaxRows) {
+		limits.MaxRows = maxRows
+	}
+	return limits, nil
+}
+
+// withLimits bounds ctx by limits. The returned RowLimit reports whether Query results were
+// truncated.
+func withLimits(ctx context.Context, limits statementLimits) (context.Context, *database.RowLimit, context.CancelFunc) {
+	rowLimit := &database.RowLimit{Max: limits.MaxRows}
+	ctx = database.WithRowLimit(ctx, rowLimit)
+	if limits.Timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, rowLimit, cancel
+	}
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	return ctx, rowLimit, cancel
+}
+
+// writeStatementError answers 504 when the statement ran into its timeout and 400 otherwise.
+func writeStatementError(w http.ResponseWriter, ctx context.Context, err error, limits statementLimits) {
+	err = statementError(ctx, err, limits)
+	writeError(w, statementStatus(err), err)
+}
+
+// statementError replaces err with ErrStatementTimeout when the statement ran into its
+// timeout.
+func statementError(ctx context.Context, err error, limits statementLimits) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: exceeded %s", ErrStatementTimeout, limits.Timeout)
+	}
+	return err
+}
+
+// statementStatus is the HTTP status for a failed statement: 409 when it was cancelled, 504
+// when it timed out, 403 when it would write to a read-only connection or without the
+// access to do so and 400 otherwise.
+func statementStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrQueryCancelled):
+		return http.StatusConflict
+	case errors.Is(err, ErrStatementTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrConnectionReadOnly), errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryLimits(t *testing.T) {
+	conn, opts, err := splitConnectionFlags(":memory:;readonly;timeout=100ms;maxrows=3")
+	if err != nil || conn != ":memory:" || !opts.ReadOnly || opts.Timeout != 100*time.Millisecond || opts.MaxRows != 3 {
+		t.Fatalf("unexpected flags parse: %q %+v %v", conn, opts, err)
+	}
+	for _, bad := range []string{"app.db;timeout=abc", "app.db;maxrows=-1", "app.db;timeout=-1s;readonly"} {
+		if _, _, err := splitConnectionFlags(bad); err == nil {
+			t.Errorf("%s: expected an invalid flag to be reported", bad)
+		}
+	}
+	opts.ReadOnly = false
+
+	api := newTestAPIOn(t, conn, opts)
+	query := func(body string) (int, map[string]any) {
+		rec := api.do(http.MethodPost, "/api/query", body)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+		return rec.Code, resp
+	}
+	const series = `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n LIMIT 10) SELECT i FROM n`
+
+	code, resp := query(`{"query":"` + series + `"}`)
+	if code != http.StatusOK || len(resp["rows"].([]any)) != 3 || resp["truncated"] != true {
+		t.Fatalf("expected 3 truncated rows, got %d %v", code, resp)
+	}
+	code, resp = query(`{"query":"` + series + `","maxRows":20}`)
+	if code != http.StatusOK || len(resp["rows"].([]any)) != 10 || resp["truncated"] != false {
+		t.Fatalf("expected the override to return all 10 rows, got %d %v", code, resp)
+	}
+
+	slow := `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n`
+	code, resp = query(`{"query":"` + slow + `"}`)
+	if code != http.StatusGatewayTimeout || !strings.Contains(resp["error"].(string), "timeout") {
+		t.Fatalf("expected a statement timeout, got %d %v", code, resp)
+	}
+	if code, resp = query(`{"query":"SELECT 1","timeout":"soon"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid timeout, got %d %v", code, resp)
+	}
+
+	rec := api.do(http.MethodPost, "/api/connections", `{"name":"bad","connString":":memory:;timeout=abc"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a connection with an invalid timeout, got %d %s", rec.Code, rec.Body)
+	}
+}
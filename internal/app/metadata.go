@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"sqlite-gui/pkg/database"
+	"sqlite-gui/pkg/database/sqlite"
+)
+
+var ErrMetadataDisabled = errors.New("metadata store is disabled (start the server with -metadata-file)")
+
+// Metadata is the local SQLite file where the server keeps its own state: the query history
+// and the saved queries.
+type Metadata struct {
+	db database.Database
+}
+
+var metadataSchema = []string{
+	`CREATE TABLE IF NOT EXISTS history (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		time        TEXT NOT NULL,
+		connection  TEXT NOT NULL,
+		kind        TEXT NOT NULL,
+		user        TEXT,
+		statement   TEXT NOT NULL,
+		args        TEXT,
+		duration_ms REAL NOT NULL,
+		row_count   INTEGER NOT NULL,
+		error       TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS history_user_time ON history (user, time)`,
+	// External-content index over the statement and error text, kept in sync by triggers.
+	`CREATE VIRTUAL TABLE IF NOT EXISTS history_fts USING fts5(
+		statement, error, content='history', content_rowid='id'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS history_fts_insert AFTER INSERT ON history BEGIN
+		INSERT INTO history_fts (rowid, statement, error) VALUES (new.id, new.statement, new.error);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS history_fts_delete AFTER DELETE ON history BEGIN
+		INSERT INTO history_fts (history_fts, rowid, statement, error) VALUES ('delete', old.id, old.statement, old.error);
+	END`,
+	`CREATE TABLE IF NOT EXISTS saved_queries (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		name         TEXT NOT NULL,
+		description  TEXT,
+		tags         TEXT,
+		connection   TEXT,
+		sql          TEXT NOT NULL,
+		params       TEXT,
+		slug         TEXT,
+		api_key_hash TEXT,
+		created_by   TEXT,
+		created      TEXT NOT NULL,
+		updated      TEXT NOT NULL
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS saved_queries_slug ON saved_queries (slug)`,
+}
+
+func OpenMetadata(ctx context.Context, path string) (*Metadata, error) {
+	db := sqlite.New()
+	if err := db.Connect(ctx, path); err != nil {
+		return nil, err
+	}
+	for _, stmt := range metadataSchema {
+		if _, err := db.Exec(ctx, stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &Metadata{db: db}, nil
+}
+
+func (m *Metadata) Close() error {
+	return m.db.Close()
+}
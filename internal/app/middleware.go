@@ -1,6 +1,14 @@
 package app
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"sqlite-gui/pkg/database"
+)
+
+var ErrCrossOrigin = errors.New("cross-origin request rejected")
 
 type middleware func(next http.Handler) http.Handler
 
@@ -19,13 +27,59 @@ func handle(mux *http.ServeMux, pattern string, handler http.Handler, middleware
 // 	mux.Handle(pattern, handler)
 // }
 
+// schemaMiddleware makes table calls address the schema named by ?schema= (PostgreSQL).
+func schemaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if schema := r.URL.Query().Get("schema"); schema != "" {
+			r = r.WithContext(database.WithSchema(r.Context(), schema))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOriginMiddleware rejects state-changing requests sent by a browser from a page of
+// another origin. Such pages can post forms and text/plain bodies that carry the user's
+// session cookie or cached basic-auth credentials; browsers mark them with Sec-Fetch-Site,
+// or with an Origin that differs from the host. Requests with neither header, such as
+// those from curl, pass.
+func sameOriginMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !sameOrigin(r) {
+				writeError(w, http.StatusForbidden, ErrCrossOrigin)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin reports whether r was sent from a page of this server, or not from a browser.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
 // corsMiddleware adds CORS headers to allow cross-origin requests
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from any origin (use specific origin in production)
+		// Allow reads from any origin; sameOriginMiddleware rejects cross-origin writes.
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		// Handle preflight requests
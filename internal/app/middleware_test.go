@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSameOriginMiddleware(t *testing.T) {
+	handler := sameOriginMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	cases := []struct {
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{http.MethodPost, nil, http.StatusOK},
+		{http.MethodPost, map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+		{http.MethodPost, map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, http.StatusOK},
+		{http.MethodPost, map[string]string{"Origin": "https://evil.test", "Content-Type": "text/plain"}, http.StatusForbidden},
+		{http.MethodPost, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{http.MethodDelete, map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "http://other.example.com"}, http.StatusForbidden},
+		{http.MethodGet, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.test"}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "http://example.com/api/exec", strings.NewReader(`{"query":"DELETE FROM t"}`))
+		for name, value := range tc.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %v: got %d, want %d", tc.method, tc.headers, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrNotifyUnsupported = errors.New("LISTEN/NOTIFY is only supported on PostgreSQL connections")
+
+type notifier interface {
+	Listen(ctx context.Context, channels []string, handle func(channel, payload string)) error
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// notification is one NOTIFY delivered to a listener. Pretty holds the payload re-indented
+// when it is JSON. A final notification with only Error set reports a failed LISTEN.
+type notification struct {
+	Channel string    `json:"channel,omitempty"`
+	Payload string    `json:"payload,omitempty"`
+	Pretty  string    `json:"pretty,omitempty"`
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func newNotification(channel, payload string) notification {
+	n := notification{Channel: channel, Payload: payload, Time: time.Now()}
+	var buf bytes.Buffer
+	if json.Valid([]byte(payload)) && json.Indent(&buf, []byte(payload), "", "  ") == nil {
+		n.Pretty = buf.String()
+	}
+	return n
+}
+
+func (api *API) useNotifier(w http.ResponseWriter, r *http.Request) (notifier, bool) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return nil, false
+	}
+	n, ok := db.(notifier)
+	if !ok {
+		writeError(w, http.StatusBadRequest, ErrNotifyUnsupported)
+		return nil, false
+	}
+	return n, true
+}
+
+// listenChannels subscribes a dedicated connection to one or more channels (repeat
+// ?channel= or separate them with commas) and streams every payload as an SSE
+// "notification" event.
+// curl: curl -N "http://localhost:3000/api/notify/listen?db=pg&channel=orders&channel=jobs"
+func (api *API) listenChannels(w http.ResponseWriter, r *http.Request) {
+	n, ok := api.useNotifier(w, r)
+	if !ok {
+		return
+	}
+	var channels []string
+	for _, param := range r.URL.Query()["channel"] {
+		for _, channel := range strings.Split(param, ",") {
+			if channel = strings.TrimSpace(channel); channel != "" {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	if len(channels) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("at least one channel is required"))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events := make(chan notification, eventBuffer)
+	go func() {
+		defer close(events)
+		err := n.Listen(ctx, channels, func(channel, payload string) {
+			select {
+			case events <- newNotification(channel, payload):
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			select {
+			case events <- notification{Time: time.Now(), Error: err.Error()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	streamEvents(w, r, "notification", events)
+}
+
+// sendNotification sends a NOTIFY with an optional payload.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"channel":"orders","payload":"{\"id\":7}"}' "http://localhost:3000/api/notify?db=pg"
+func (api *API) sendNotification(w http.ResponseWriter, r *http.Request) {
+	n, ok := api.useNotifier(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Channel string `json:"channel"`
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Channel) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("channel is required"))
+		return
+	}
+	if err := n.Notify(r.Context(), req.Channel, req.Payload); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
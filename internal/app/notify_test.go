@@ -0,0 +1,24 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewNotificationPrettyPrintsJSON(t *testing.T) {
+	n := newNotification("orders", `{"id":7,"items":[1,2]}`)
+	if !strings.Contains(n.Pretty, "\n  \"id\": 7") {
+		t.Fatalf("expected indented JSON, got %q", n.Pretty)
+	}
+	if n := newNotification("orders", "plain text"); n.Pretty != "" {
+		t.Fatalf("expected no pretty form for a non-JSON payload, got %q", n.Pretty)
+	}
+}
+
+func TestNotifyRequiresPostgres(t *testing.T) {
+	rec := newTestAPI(t).do(http.MethodPost, "/api/notify", `{"channel":"orders"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "PostgreSQL") {
+		t.Fatalf("expected 400 for SQLite, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+// apiKeyHeader carries the key of a protected /q/{slug} endpoint.
+const apiKeyHeader = "X-API-Key"
+
+var (
+	ErrSlugTaken     = errors.New("slug is already published")
+	ErrInvalidAPIKey = errors.New("missing or invalid API key")
+)
+
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// PublishSavedQuery publishes query id at /q/{slug}, protected by the key whose hash is
+// apiKeyHash unless it is empty. An empty slug unpublishes the query.
+func (m *Metadata) PublishSavedQuery(ctx context.Context, id int64, slug, apiKeyHash string) error {
+	if slug != "" {
+		rows, err := m.db.Find(ctx, "saved_queries", database.Key{"slug": slug}, 1, 0)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 && rows[0]["id"] != id {
+			return fmt.Errorf("%w: %s", ErrSlugTaken, slug)
+		}
+	}
+	res, err := m.db.Exec(ctx, "UPDATE saved_queries SET slug = ?, api_key_hash = ? WHERE id = ?",
+		nullString(slug), nullString(apiKeyHash), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSavedQueryNotFound
+	}
+	return nil
+}
+
+// GetPublishedQuery returns the saved query published at slug.
+func (m *Metadata) GetPublishedQuery(ctx context.Context, slug string) (SavedQuery, error) {
+	rows, err := m.db.Find(ctx, "saved_queries", database.Key{"slug": slug}, 1, 0)
+	if err != nil {
+		return SavedQuery{}, err
+	}
+	if len(rows) == 0 {
+		return SavedQuery{}, fmt.Errorf("%w: %s", ErrSavedQueryNotFound, slug)
+	}
+	return decodeSavedQueryRow(rows[0])
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkAPIKey reports whether key opens the published endpoint of q.
+func (q SavedQuery) checkAPIKey(key string) bool {
+	return key != "" && subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(q.apiKeyHash)) == 1
+}
+
+// publishSavedQuery publishes a saved query at GET /q/{slug}. With "apiKey": true a new key
+// is generated and returned once; callers then pass it in the X-API-Key header. Publishing
+// again replaces the slug and key. Queries on writable in-memory SQLite connections cannot
+// be published, since published queries run over a separate read-only connection.
+// curl: curl -X PUT -H "Content-Type: application/json" -d '{"slug":"orders-by-user","apiKey":true}' "http://localhost:3000/api/saved-queries/1/publish"
+func (api *API) publishSavedQuery(w http.ResponseWriter, r *http.Request) {
+	saved, ok := api.loadSavedQuery(w, r, RoleAdmin)
+	if !ok {
+		return
+	}
+	var req struct {
+		Slug   string `json:"slug"`
+		APIKey bool   `json:"apiKey"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !slugPattern.MatchString(req.Slug) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid slug %q (letters, digits, '-', '_' and '.')", req.Slug))
+		return
+	}
+	for _, p := range saved.Params {
+		if p.Name == "format" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%w: :format is reserved for the output format of published queries", ErrInvalidParam))
+			return
+		}
+	}
+	if _, err := api.connections.GetReadOnly(r.Context(), saved.Connection); err != nil {
+		writeError(w, readOnlyStatus(err), err)
+		return
+	}
+	var key, keyHash string
+	if req.APIKey {
+		var err error
+		if key, err = GenerateToken(); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		keyHash = hashAPIKey(key)
+	}
+	err := api.metadata.PublishSavedQuery(r.Context(), saved.ID, req.Slug, keyHash)
+	if errors.Is(err, ErrSlugTaken) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := map[string]any{"status": "ok", "slug": req.Slug, "url": "/q/" + req.Slug}
+	if key != "" {
+		resp["apiKey"] = key
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// unpublishSavedQuery removes the /q/{slug} endpoint of a saved query.
+// curl: curl -X DELETE "http://localhost:3000/api/saved-queries/1/publish"
+func (api *API) unpublishSavedQuery(w http.ResponseWriter, r *http.Request) {
+	saved, ok := api.loadSavedQuery(w, r, RoleAdmin)
+	if !ok {
+		return
+	}
+	if err := api.metadata.PublishSavedQuery(r.Context(), saved.ID, "", ""); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// publishedQuery runs the saved query published at slug with the URL query parameters as
+// its values, always over a read-only connection. Protected endpoints take their API key in
+// the X-API-Key header; others are open to the viewers of the connection. Rows are returned
+// as JSON, or as CSV with ?format=csv or "Accept: text/csv".
+// curl: curl -H "X-API-Key: $KEY" "http://localhost:3000/q/orders-by-user?user_id=42&format=csv"
+func (api *API) publishedQuery(w http.ResponseWriter, r *http.Request) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return
+	}
+	saved, err := api.metadata.GetPublishedQuery(r.Context(), r.PathValue("slug"))
+	if errors.Is(err, ErrSavedQueryNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	key := r.Header.Get(apiKeyHeader)
+	switch {
+	case saved.Protected:
+		if !saved.checkAPIKey(key) {
+			writeError(w, http.StatusUnauthorized, ErrInvalidAPIKey)
+			return
+		}
+	case r.Context().Value(keyOnlyKey{}) != nil:
+		// Only the key got the request past authentication, and this endpoint takes none.
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("%w: %s takes no API key", ErrInvalidAPIKey, r.URL.Path))
+		return
+	default:
+		if err := api.savedQueryAccess(r, saved, RoleViewer); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	params := r.URL.Query()
+	format := params.Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %q (json or csv)", format))
+		return
+	}
+	params.Del("format")
+	values := make(map[string]any, len(params))
+	for name := range params {
+		values[name] = params.Get(name)
+	}
+
+	r = onConnection(r, saved.Connection)
+	r = r.WithContext(database.WithReadOnly(r.Context()))
+	db, err := api.connections.GetReadOnly(r.Context(), saved.Connection)
+	if err != nil {
+		writeError(w, readOnlyStatus(err), err)
+		return
+	}
+	statement, args, err := saved.bind(values, db.Placeholder)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := api.limitsFor(r, "", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, ok := api.runQuery(w, r, db, statement, args, limits)
+	if !ok {
+		return
+	}
+	if format == "csv" {
+		writeCSV(w, result)
+		return
+	}
+	rows := json.RawMessage(result.Encoded)
+	if result.Rows == nil {
+		rows = json.RawMessage("[]")
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"columns":   result.Columns,
+		"rows":      rows,
+		"truncated": result.Truncated,
+	})
+}
+
+// readOnlyStatus is the HTTP status for an error of ConnectionManager.GetReadOnly.
+func readOnlyStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrConnectionMiss):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInMemoryConnection):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// writeCSV writes the rows of result with a header line of its columns. Truncated results
+// are flagged with an X-Truncated header.
+func writeCSV(w http.ResponseWriter, result queryResult) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if result.Truncated {
+		w.Header().Set("X-Truncated", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	out := csv.NewWriter(w)
+	_ = out.Write(result.Columns)
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, col := range result.Columns {
+			switch v := row[col].(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.Format(time.RFC3339Nano)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		_ = out.Write(record)
+	}
+	out.Flush()
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublishedQuery(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPIOn(t, filepath.Join(t.TempDir(), "app.db"), ConnectionOptions{})
+	api.exec(
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, note TEXT)",
+		"INSERT INTO orders (user_id, note) VALUES (1, 'first'), (1, 'say \"hi\", twice'), (2, 'other')",
+	)
+	api.useMetadata()
+	for _, body := range []string{
+		`{"name":"orders","sql":"SELECT id, note FROM orders WHERE user_id = :user_id ORDER BY id","params":[{"name":"user_id","type":"integer","required":true}]}`,
+		`{"name":"purge","sql":"DELETE FROM orders WHERE user_id = :user_id","params":[{"name":"user_id","type":"integer"}]}`,
+	} {
+		if rec := api.do(http.MethodPost, "/api/saved-queries", body); rec.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", rec.Code, rec.Body)
+		}
+	}
+
+	rec := api.do(http.MethodPut, "/api/saved-queries/1/publish", `{"slug":"orders-by-user","apiKey":true}`)
+	var published struct {
+		APIKey string `json:"apiKey"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &published); err != nil || published.APIKey == "" {
+		t.Fatalf("publish: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodPut, "/api/saved-queries/2/publish", `{"slug":"orders-by-user"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected a taken slug to conflict, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := api.do(http.MethodGet, "/q/orders-by-user?user_id=1", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the key to be required, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodGet, "/q/orders-by-user?user_id=one", "", apiKeyHeader, published.APIKey); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a type error, got %d %s", rec.Code, rec.Body)
+	}
+	rec = api.do(http.MethodGet, "/q/orders-by-user?user_id=1", "", apiKeyHeader, published.APIKey)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"columns":["id","note"]`) {
+		t.Fatalf("json: %d %s", rec.Code, rec.Body)
+	}
+	rec = api.do(http.MethodGet, "/q/orders-by-user?user_id=1&format=csv", "", apiKeyHeader, published.APIKey)
+	if want := "id,note\n1,first\n2,\"say \"\"hi\"\", twice\"\n"; rec.Body.String() != want {
+		t.Fatalf("csv: got %q, want %q", rec.Body, want)
+	}
+
+	if rec := api.do(http.MethodPut, "/api/saved-queries/2/publish", `{"slug":"purge"}`); rec.Code != http.StatusOK {
+		t.Fatalf("publish purge: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodGet, "/q/purge?user_id=2", ""); rec.Code == http.StatusOK {
+		t.Fatalf("expected the published delete to be rejected, got %s", rec.Body)
+	}
+	if n, _ := api.db.Count(ctx, "orders"); n != 3 {
+		t.Fatalf("expected the rows to survive, %d left", n)
+	}
+	if rec := api.do(http.MethodPost, "/api/saved-queries/2/run", `{"params":{"user_id":2}}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected the unpublished run to keep write access, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := api.do(http.MethodDelete, "/api/saved-queries/1/publish", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unpublish: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodGet, "/q/orders-by-user?user_id=1", "", apiKeyHeader, published.APIKey); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after unpublishing, got %d", rec.Code)
+	}
+
+	// Behind authentication, open endpoints need a login and reject stray keys.
+	if rec := api.do(http.MethodPut, "/api/saved-queries/1/publish", `{"slug":"open"}`); rec.Code != http.StatusOK {
+		t.Fatalf("publish open: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodGet, "/q/open?user_id=1", "", apiKeyHeader, published.APIKey); rec.Code != http.StatusOK {
+		t.Fatalf("expected a stray key to be ignored without authentication, got %d %s", rec.Code, rec.Body)
+	}
+	handler := NewAuthenticator("tok", nil).Middleware(api.mux)
+	for _, step := range []struct {
+		header, value string
+		want          int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{apiKeyHeader, published.APIKey, http.StatusUnauthorized},
+		{"Authorization", "Bearer tok", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/q/open?user_id=1", nil)
+		if step.header != "" {
+			req.Header.Set(step.header, step.value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != step.want {
+			t.Fatalf("%s: expected %d, got %d %s", step.header, step.want, rec.Code, rec.Body)
+		}
+	}
+
+	// In-memory databases cannot be reopened read-only, so their queries are not published.
+	if err := api.connections.Add(ctx, "scratch", ":memory:"); err != nil {
+		t.Fatalf("add scratch: %v", err)
+	}
+	if rec := api.do(http.MethodPost, "/api/saved-queries", `{"name":"one","connection":"scratch","sql":"SELECT 1"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodPut, "/api/saved-queries/3/publish", `{"slug":"scratch"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected publishing on an in-memory connection to fail, got %d %s", rec.Code, rec.Body)
+	}
+}
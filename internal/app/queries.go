@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueryMiss      = errors.New("query not found")
+	ErrQueryCancelled = errors.New("query cancelled")
+)
+
+// runningQuery is a /api/query, /api/exec or /api/explain statement that has not finished yet.
+type runningQuery struct {
+	ID         string    `json:"id"`
+	Connection string    `json:"connection"`
+	Kind       string    `json:"kind"` // "query", "exec" or "explain"
+	Statement  string    `json:"statement"`
+	User       string    `json:"user,omitempty"`
+	Started    time.Time `json:"started"`
+
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// QueryTracker keeps the in-flight statements of every connection so they can be listed
+// and cancelled from another request.
+type QueryTracker struct {
+	mu      sync.Mutex
+	nextID  int64
+	running map[string]*runningQuery
+}
+
+func NewQueryTracker() *QueryTracker {
+	return &QueryTracker{running: make(map[string]*runningQuery)}
+}
+
+// Start registers a statement and returns the context to run it with. The caller must call
+// finish when the statement returns; finish reports whether it was cancelled through Cancel.
+func (t *QueryTracker) Start(ctx context.Context, q runningQuery) (context.Context, *runningQuery, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	t.mu.Lock()
+	t.nextID++
+	q.ID = strconv.FormatInt(t.nextID, 10)
+	q.Started = time.Now()
+	q.cancel = cancel
+	entry := &q
+	t.running[q.ID] = entry
+	t.mu.Unlock()
+
+	finish := func() bool {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.running, entry.ID)
+		cancel()
+		return entry.cancelled
+	}
+	return ctx, entry, finish
+}
+
+// List returns the running statements, oldest first.
+func (t *QueryTracker) List() []runningQuery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	queries := make([]runningQuery, 0, len(t.running))
+	for _, q := range t.running {
+		queries = append(queries, *q)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Started.Before(queries[j].Started) })
+	return queries
+}
+
+func (t *QueryTracker) Get(id string) (runningQuery, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, ok := t.running[id]
+	if !ok {
+		return runningQuery{}, false
+	}
+	return *q, true
+}
+
+// Cancel cancels the context of the statement id. The driver then interrupts it:
+// sqlite3_interrupt for SQLite and a cancel request for PostgreSQL.
+func (t *QueryTracker) Cancel(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, ok := t.running[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrQueryMiss, id)
+	}
+	q.cancelled = true
+	q.cancel()
+	return nil
+}
+
+// trackQuery registers the statement of r with the query tracker.
+func (api *API) trackQuery(r *http.Request, kind, statement string) (context.Context, *runningQuery, func() bool) {
+	return api.queries.Start(r.Context(), runningQuery{
+		Connection: api.connectionName(r),
+		Kind:       kind,
+		Statement:  statement,
+		User:       Principal(r.Context()),
+	})
+}
+
+// canCancel reports whether the user of r may cancel q: its own statements, or any
+// statement on a connection they administer.
+func (api *API) canCancel(r *http.Request, q runningQuery) bool {
+	if api.access == nil {
+		return true
+	}
+	user := Principal(r.Context())
+	return q.User == user || api.roleOn(user, q.Connection).Allows(RoleAdmin)
+}
+
+// listQueries returns the running /api/query and /api/exec statements on the connections
+// the user may view, optionally limited to one connection with ?db=.
+// curl: curl -X GET "http://localhost:3000/api/queries?db=db1"
+func (api *API) listQueries(w http.ResponseWriter, r *http.Request) {
+	connection := r.URL.Query().Get("db")
+	user := Principal(r.Context())
+	queries := []runningQuery{}
+	for _, q := range api.queries.List() {
+		if connection != "" && q.Connection != connection {
+			continue
+		}
+		if api.access != nil && !api.roleOn(user, q.Connection).Allows(RoleViewer) {
+			continue
+		}
+		queries = append(queries, q)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"queries": queries})
+}
+
+// cancelQuery cancels a running statement. Users may cancel their own statements; admins of
+// the connection may cancel anyone's.
+// curl: curl -X DELETE http://localhost:3000/api/queries/7
+func (api *API) cancelQuery(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	q, ok := api.queries.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrQueryMiss, id))
+		return
+	}
+	if !api.canCancel(r, q) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("%w: only the query's user or an admin of %s may cancel it", ErrForbidden, q.Connection))
+		return
+	}
+	if err := api.queries.Cancel(id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
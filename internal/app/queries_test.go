@@ -0,0 +1,58 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelRunningQuery(t *testing.T) {
+	api := newTestAPI(t)
+
+	slow := `{"query":"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n"}`
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := api.do(http.MethodPost, "/api/query", slow)
+		done <- rec
+	}()
+
+	var id string
+	for deadline := time.Now().Add(5 * time.Second); id == "" && time.Now().Before(deadline); {
+		rec := api.do(http.MethodGet, "/api/queries?db=main", "")
+		var body struct {
+			Queries []runningQuery `json:"queries"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(body.Queries) > 0 {
+			id = body.Queries[0].ID
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if id == "" {
+		t.Fatalf("query never showed up in /api/queries")
+	}
+
+	rec := api.do(http.MethodDelete, "/api/queries/"+id, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel: %d %s", rec.Code, rec.Body)
+	}
+	select {
+	case rec := <-done:
+		if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "cancelled") {
+			t.Fatalf("expected the query to be cancelled, got %d %s", rec.Code, rec.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("query kept running after cancel")
+	}
+
+	rec = api.do(http.MethodDelete, "/api/queries/"+id, "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a finished query, got %d", rec.Code)
+	}
+}
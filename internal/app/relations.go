@@ -0,0 +1,311 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+	"sqlite-gui/pkg/database/postgresql"
+)
+
+const defaultRelationLimit = 50
+
+// foreignKey is a whole FOREIGN KEY constraint, with the parts of composite keys grouped together.
+type foreignKey struct {
+	Constraint string   `json:"constraint"`
+	Schema     string   `json:"schema,omitempty"` // Set when Table is in another schema than the one addressed.
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	RefSchema  string   `json:"refSchema,omitempty"` // Set when RefTable is in another schema.
+	RefTable   string   `json:"refTable"`
+	RefColumns []string `json:"refColumns"`
+}
+
+// groupForeignKeys collects the per-column foreign keys of table into whole constraints.
+func groupForeignKeys(table string, cols []database.Column) []foreignKey {
+	var (
+		result []foreignKey
+		index  = make(map[string]int)
+	)
+	for _, col := range cols {
+		for _, fk := range col.ForeignKeys {
+			id := fk.Constraint
+			if id == "" {
+				id = fk.FromCol + "->" + fk.RefSchema + "." + fk.RefTable
+			}
+			i, ok := index[id]
+			if !ok {
+				i = len(result)
+				index[id] = i
+				result = append(result, foreignKey{Constraint: fk.Constraint, Table: table, RefSchema: fk.RefSchema, RefTable: fk.RefTable})
+			}
+			result[i].Columns = append(result[i].Columns, fk.FromCol)
+			result[i].RefColumns = append(result[i].RefColumns, fk.ToCol)
+		}
+	}
+	return result
+}
+
+// refContext returns ctx addressing the schema of the referenced table.
+func (fk foreignKey) refContext(ctx context.Context) context.Context {
+	if fk.RefSchema == "" {
+		return ctx
+	}
+	return database.WithSchema(ctx, fk.RefSchema)
+}
+
+// context returns ctx addressing the schema of the referencing table.
+func (fk foreignKey) context(ctx context.Context) context.Context {
+	if fk.Schema == "" {
+		return ctx
+	}
+	return database.WithSchema(ctx, fk.Schema)
+}
+
+// refName is the referenced table, qualified with its schema when that differs from the table's.
+func (fk foreignKey) refName() string {
+	if fk.RefSchema == "" {
+		return fk.RefTable
+	}
+	return fk.RefSchema + "." + fk.RefTable
+}
+
+// resolveRefColumns fills in referenced columns left implicit (SQLite's "REFERENCES parent")
+// with the parent's primary key.
+func resolveRefColumns(ctx context.Context, db database.Database, fk *foreignKey) error {
+	missing := false
+	for _, c := range fk.RefColumns {
+		if c == "" {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	cols, err := db.Columns(fk.refContext(ctx), fk.RefTable)
+	if err != nil {
+		return err
+	}
+	var pk []database.Column
+	for _, col := range cols {
+		if col.PrimaryKey {
+			pk = append(pk, col)
+		}
+	}
+	sort.Slice(pk, func(i, j int) bool { return pk[i].PrimaryKeyIndex < pk[j].PrimaryKeyIndex })
+	if len(pk) != len(fk.Columns) {
+		return errors.New("cannot resolve the referenced columns of " + fk.Table + " -> " + fk.refName())
+	}
+	for i := range fk.RefColumns {
+		fk.RefColumns[i] = pk[i].Name
+	}
+	return nil
+}
+
+// matchKey pairs the values of row's from columns with the to columns. It returns false when
+// any value is NULL, since such a row does not take part in the relationship.
+func matchKey(row database.Row, from, to []string) (database.Key, bool) {
+	key := database.Key{}
+	for i, col := range from {
+		value, ok := row[col]
+		if !ok || value == nil {
+			return nil, false
+		}
+		key[to[i]] = value
+	}
+	return key, true
+}
+
+// rowRelations serves the relationship views of a single row. ServeMux only allows a
+// trailing {id...} wildcard, so the view is taken from the last path segment.
+//
+//	curl: curl -X GET "http://localhost:3000/api/tables/orders/rows/7/references?db=db1"
+//	curl: curl -X GET "http://localhost:3000/api/tables/users/rows/1/referenced-by?limit=20&offset=0&db=db1"
+func (api *API) rowRelations(w http.ResponseWriter, r *http.Request) {
+	rawID := r.PathValue("id")
+	switch {
+	case strings.HasSuffix(rawID, "/references"):
+		api.rowReferences(w, r, strings.TrimSuffix(rawID, "/references"))
+	case strings.HasSuffix(rawID, "/referenced-by"):
+		api.rowReferencedBy(w, r, strings.TrimSuffix(rawID, "/referenced-by"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// rowReferences returns the parent rows the selected row points to, one entry per foreign key.
+func (api *API) rowReferences(w http.ResponseWriter, r *http.Request, rawID string) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	row, ok := findRow(w, r, db, table, rawID)
+	if !ok {
+		return
+	}
+	cols, err := db.Columns(r.Context(), table)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type reference struct {
+		foreignKey
+		Row database.Row `json:"row"`
+	}
+	references := []reference{}
+	for _, fk := range groupForeignKeys(table, cols) {
+		if err := resolveRefColumns(r.Context(), db, &fk); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		ref := reference{foreignKey: fk}
+		if match, ok := matchKey(row, fk.Columns, fk.RefColumns); ok {
+			parents, err := db.Find(fk.refContext(r.Context()), fk.RefTable, match, 1, 0)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if len(parents) > 0 {
+				ref.Row = parents[0]
+			}
+		}
+		references = append(references, ref)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"references": references})
+}
+
+// rowReferencedBy lists the child rows in other tables that point at the selected row,
+// grouped by foreign key. limit/offset paginate each group (default limit 50). On PostgreSQL
+// child tables in every schema are searched.
+func (api *API) rowReferencedBy(w http.ResponseWriter, r *http.Request, rawID string) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	limit := queryInt(r, "limit")
+	if limit <= 0 {
+		limit = defaultRelationLimit
+	}
+	offset := queryInt(r, "offset")
+
+	row, ok := findRow(w, r, db, table, rawID)
+	if !ok {
+		return
+	}
+	keys, err := referencingKeys(r.Context(), db, table)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type referrer struct {
+		foreignKey
+		Rows  []database.Row `json:"rows"`
+		Total int64          `json:"total"`
+	}
+	referrers := []referrer{}
+	for _, fk := range keys {
+		if err := resolveRefColumns(r.Context(), db, &fk); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		match, ok := matchKey(row, fk.RefColumns, fk.Columns)
+		if !ok {
+			continue
+		}
+		ctx := fk.context(r.Context())
+		total, err := db.CountMatching(ctx, fk.Table, match)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		rows, err := db.Find(ctx, fk.Table, match, limit, offset)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		referrers = append(referrers, referrer{foreignKey: fk, Rows: rows, Total: total})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"referencedBy": referrers})
+}
+
+type referrerLister interface {
+	Referrers(ctx context.Context, table string) ([]postgresql.Referrer, error)
+}
+
+// referencingKeys returns the foreign keys that reference table. Drivers that can look the
+// constraints up across schemas are asked for them; otherwise every table in the addressed
+// schema is searched.
+func referencingKeys(ctx context.Context, db database.Database, table string) ([]foreignKey, error) {
+	lister, ok := db.(referrerLister)
+	if !ok {
+		tables, err := db.Tables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var keys []foreignKey
+		for _, child := range tables {
+			cols, err := db.Columns(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+			for _, fk := range groupForeignKeys(child, cols) {
+				if fk.RefTable == table && fk.RefSchema == "" {
+					keys = append(keys, fk)
+				}
+			}
+		}
+		return keys, nil
+	}
+
+	refs, err := lister.Referrers(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	var keys []foreignKey
+	for i := 0; i < len(refs); {
+		// Referrers come ordered by table, so each child's columns are read once.
+		child := refs[i]
+		constraints := map[string]bool{}
+		for ; i < len(refs) && refs[i].Schema == child.Schema && refs[i].Table == child.Table; i++ {
+			constraints[refs[i].Constraint] = true
+		}
+		probe := foreignKey{Schema: child.Schema}
+		cols, err := db.Columns(probe.context(ctx), child.Table)
+		if err != nil {
+			return nil, err
+		}
+		for _, fk := range groupForeignKeys(child.Table, cols) {
+			if constraints[fk.Constraint] {
+				fk.Schema = child.Schema
+				keys = append(keys, fk)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// findRow loads the row addressed by rawID and ?pk=, writing a 404 when it does not exist.
+func findRow(w http.ResponseWriter, r *http.Request, db database.Database, table, rawID string) (database.Row, bool) {
+	key, err := buildKey(primaryKeyColumns(r.URL.Query().Get("pk")), parsePathID(rawID))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	rows, err := db.Find(r.Context(), table, key, 1, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if len(rows) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("row not found"))
+		return nil, false
+	}
+	return rows[0], true
+}
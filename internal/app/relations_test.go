@@ -0,0 +1,55 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRowRelations(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec(`
+		CREATE TABLE teams (org_id INTEGER, id INTEGER, name TEXT, PRIMARY KEY (org_id, id));
+		CREATE TABLE members (id INTEGER PRIMARY KEY, org_id INTEGER, team_id INTEGER,
+			FOREIGN KEY (org_id, team_id) REFERENCES teams (org_id, id));
+		INSERT INTO teams VALUES (1, 1, 'core'), (1, 2, 'web'), (2, 1, 'other');
+		INSERT INTO members VALUES (10, 1, 1), (11, 1, 1), (12, 2, 1);
+	`)
+
+	rec := api.do(http.MethodGet, "/api/tables/members/rows/10/references", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("references: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var refs struct {
+		References []struct {
+			RefTable string         `json:"refTable"`
+			Columns  []string       `json:"columns"`
+			Row      map[string]any `json:"row"`
+		} `json:"references"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&refs); err != nil {
+		t.Fatalf("decode references: %v", err)
+	}
+	if len(refs.References) != 1 || len(refs.References[0].Columns) != 2 || refs.References[0].Row["name"] != "core" {
+		t.Fatalf("unexpected references %+v", refs.References)
+	}
+
+	rec = api.do(http.MethodGet, "/api/tables/teams/rows/1,1/referenced-by?pk=org_id,id&limit=1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("referenced-by: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var children struct {
+		ReferencedBy []struct {
+			Table string           `json:"table"`
+			Rows  []map[string]any `json:"rows"`
+			Total int64            `json:"total"`
+		} `json:"referencedBy"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&children); err != nil {
+		t.Fatalf("decode referenced-by: %v", err)
+	}
+	if len(children.ReferencedBy) != 1 || children.ReferencedBy[0].Table != "members" ||
+		children.ReferencedBy[0].Total != 2 || len(children.ReferencedBy[0].Rows) != 1 {
+		t.Fatalf("unexpected referenced-by %+v", children.ReferencedBy)
+	}
+}
@@ -1,62 +1,178 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"sqlite-gui/pkg/database"
+	"sqlite-gui/pkg/database/postgresql"
 )
 
 type API struct {
 	connections *ConnectionManager
+	access      *AccessControl // nil when every user may do everything
+	auditLog    *AuditLog      // nil when auditing is off
+	metadata    *Metadata      // nil when no metadata file is configured
+	undo        *UndoHistory
+	events      *EventHub
+	queries     *QueryTracker
+	slowQueries *SlowQueryLog
 }
 
 func NewAPI(connections *ConnectionManager) *API {
-	return &API{connections: connections}
+	return &API{
+		connections: connections,
+		undo:        NewUndoHistory(),
+		events:      NewEventHub(connections),
+		queries:     NewQueryTracker(),
+		slowQueries: NewSlowQueryLog(defaultSlowQueryThreshold),
+	}
+}
+
+// SetAccessControl enables per-user roles. It must be called before the server starts.
+func (api *API) SetAccessControl(access *AccessControl) {
+	api.access = access
+}
+
+// SetSlowQueryThreshold sets how long a statement must run to be listed by
+// /api/slow-queries; zero disables the slow-query log.
+func (api *API) SetSlowQueryThreshold(threshold time.Duration) {
+	api.slowQueries = NewSlowQueryLog(threshold)
+}
+
+// SetAuditLog records every data and schema change made through the API to log.
+func (api *API) SetAuditLog(log *AuditLog) {
+	api.auditLog = log
+}
+
+// SetMetadata keeps the query history and the saved queries in m.
+func (api *API) SetMetadata(m *Metadata) {
+	api.metadata = m
 }
 
 func (api *API) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/connections", api.listConnections)
-	mux.HandleFunc("POST /api/connections", api.addConnection)
-	mux.HandleFunc("POST /api/tables", api.createTable)
-	mux.HandleFunc("GET /api/tables", api.listTables)
-	mux.HandleFunc("GET /api/tables/{table}/columns", api.getColumns)
-	mux.HandleFunc("POST /api/tables/{table}/columns", api.addColumn)
-	mux.HandleFunc("DELETE /api/tables/{table}/columns/{column}", api.dropColumn)
-	mux.HandleFunc("GET /api/tables/{table}/rows", api.getRows)
-	mux.HandleFunc("POST /api/tables/{table}/rows", api.insertRow)
-	mux.HandleFunc("PUT /api/tables/{table}/rows/{id...}", api.updateRow)
-	mux.HandleFunc("DELETE /api/tables/{table}/rows/{id...}", api.deleteRow)
-	mux.HandleFunc("DELETE /api/tables/{table}", api.dropTable)
-	mux.HandleFunc("POST /api/query", api.query)
-	mux.HandleFunc("POST /api/exec", api.exec)
-}
-
-// listConnections returns all known database connections.
+	mux.HandleFunc("POST /api/connections", api.allowGlobal(RoleAdmin, api.addConnection))
+	mux.HandleFunc("GET /api/connections/{name}/databases", api.allow(RoleViewer, api.listDatabases))
+	mux.HandleFunc("POST /api/connections/{name}/databases/{database}", api.allow(RoleAdmin, api.openDatabase))
+	mux.HandleFunc("POST /api/tables", api.allow(RoleAdmin, api.writable(api.createTable)))
+	mux.HandleFunc("GET /api/schemas", api.allow(RoleViewer, api.listSchemas))
+	mux.HandleFunc("GET /api/tables", api.allow(RoleViewer, api.listTables))
+	mux.HandleFunc("GET /api/tables/{table}/columns", api.allow(RoleViewer, api.getColumns))
+	mux.HandleFunc("POST /api/tables/{table}/columns", api.allow(RoleAdmin, api.writable(api.addColumn)))
+	mux.HandleFunc("PUT /api/tables/{table}/columns/{column}", api.allow(RoleAdmin, api.writable(api.alterColumn)))
+	mux.HandleFunc("PATCH /api/tables/{table}/columns/{column}", api.allow(RoleAdmin, api.writable(api.renameColumn)))
+	mux.HandleFunc("DELETE /api/tables/{table}/columns/{column}", api.allow(RoleAdmin, api.writable(api.dropColumn)))
+	mux.HandleFunc("POST /api/tables/{table}/indexes", api.allow(RoleAdmin, api.writable(api.createIndex)))
+	mux.HandleFunc("GET /api/tables/{table}/rows", api.allow(RoleViewer, api.getRows))
+	mux.HandleFunc("POST /api/tables/{table}/rows", api.allow(RoleEditor, api.writable(api.insertRow)))
+	mux.HandleFunc("GET /api/tables/{table}/rows/{id...}", api.allow(RoleViewer, api.rowRelations))
+	mux.HandleFunc("PUT /api/tables/{table}/rows/{id...}", api.allow(RoleEditor, api.writable(api.updateRow)))
+	mux.HandleFunc("DELETE /api/tables/{table}/rows/{id...}", api.allow(RoleEditor, api.writable(api.deleteRow)))
+	mux.HandleFunc("PUT /api/tables/{table}", api.allow(RoleAdmin, api.writable(api.alterTable)))
+	mux.HandleFunc("PATCH /api/tables/{table}", api.allow(RoleAdmin, api.writable(api.renameTable)))
+	mux.HandleFunc("DELETE /api/tables/{table}", api.allow(RoleAdmin, api.writable(api.dropTable)))
+	mux.HandleFunc("GET /api/schema/graph", api.allow(RoleViewer, api.getSchemaGraph))
+	mux.HandleFunc("POST /api/query", api.allow(RoleViewer, api.query))
+	mux.HandleFunc("POST /api/exec", api.allow(RoleAdmin, api.writable(api.exec)))
+	mux.HandleFunc("POST /api/explain", api.allow(RoleViewer, api.explain))
+	mux.HandleFunc("POST /api/console", api.allow(RoleViewer, api.console))
+	mux.HandleFunc("POST /api/advisor/indexes", api.allow(RoleViewer, api.suggestIndexes))
+	mux.HandleFunc("GET /api/queries", api.listQueries)
+	mux.HandleFunc("DELETE /api/queries/{id}", api.cancelQuery)
+	mux.HandleFunc("GET /api/slow-queries", api.getSlowQueries)
+	mux.HandleFunc("GET /api/history", api.getHistory)
+	mux.HandleFunc("DELETE /api/history", api.clearHistory)
+	mux.HandleFunc("DELETE /api/history/{id}", api.deleteHistoryEntry)
+	mux.HandleFunc("GET /api/saved-queries", api.listSavedQueries)
+	mux.HandleFunc("POST /api/saved-queries", api.createSavedQuery)
+	mux.HandleFunc("GET /api/saved-queries/{id}", api.getSavedQuery)
+	mux.HandleFunc("PUT /api/saved-queries/{id}", api.updateSavedQuery)
+	mux.HandleFunc("DELETE /api/saved-queries/{id}", api.deleteSavedQuery)
+	mux.HandleFunc("POST /api/saved-queries/{id}/run", api.runSavedQuery)
+	mux.HandleFunc("PUT /api/saved-queries/{id}/publish", api.publishSavedQuery)
+	mux.HandleFunc("DELETE /api/saved-queries/{id}/publish", api.unpublishSavedQuery)
+	mux.HandleFunc("GET /q/{slug}", api.publishedQuery)
+	mux.HandleFunc("GET /api/audit", api.allowGlobal(RoleAdmin, api.getAudit))
+	mux.HandleFunc("POST /api/undo", api.allow(RoleEditor, api.writable(api.undoChange)))
+	mux.HandleFunc("POST /api/redo", api.allow(RoleEditor, api.writable(api.redoChange)))
+	mux.HandleFunc("GET /api/events", api.allow(RoleViewer, api.getEvents))
+	mux.HandleFunc("POST /api/events/triggers/{table}", api.allow(RoleAdmin, api.writable(api.installChangeTrigger)))
+	mux.HandleFunc("DELETE /api/events/triggers/{table}", api.allow(RoleAdmin, api.writable(api.removeChangeTrigger)))
+	mux.HandleFunc("GET /api/notify/listen", api.allow(RoleViewer, api.listenChannels))
+	mux.HandleFunc("POST /api/notify", api.allow(RoleEditor, api.writable(api.sendNotification)))
+	mux.HandleFunc("GET /api/activity", api.allow(RoleAdmin, api.getActivity))
+	mux.HandleFunc("POST /api/activity/{pid}/cancel", api.allow(RoleAdmin, api.cancelBackend))
+	mux.HandleFunc("POST /api/activity/{pid}/terminate", api.allow(RoleAdmin, api.terminateBackend))
+}
+
+// writable rejects requests that would modify a read-only connection with 403.
+// Dry runs only preview statements and are let through.
+func (api *API) writable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := api.connectionName(r)
+		if api.connections.ReadOnly(name) && !isDryRun(r) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%w: %s", ErrConnectionReadOnly, name))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// listConnections returns the database connections the user may view.
 // curl: curl -X GET http://localhost:3000/api/connections
 func (api *API) listConnections(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{
-		"connections": api.connections.List(),
+		"connections": api.visibleConnections(r, api.connections.List()),
 		"default":     api.connections.Default(),
 	})
 }
 
 // addConnection adds a new database connection using the given name/connection string.
-// curl: curl -X POST -H "Content-Type: application/json" -d '{"name":"main","connString":":memory:"}' http://localhost:3000/api/connections
+// Set "readOnly" (or append ";readonly" to connString) to reject writes on the connection,
+// and "timeout"/"maxRows" (";timeout=30s;maxrows=10000") to limit /api/query statements.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"name":"main","connString":":memory:","readOnly":false,"timeout":"30s","maxRows":10000}' http://localhost:3000/api/connections
 func (api *API) addConnection(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Name       string `json:"name"`
 		ConnString string `json:"connString"`
+		ReadOnly   bool   `json:"readOnly"`
+		Timeout    string `json:"timeout"`
+		MaxRows    int    `json:"maxRows"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	if strings.TrimSpace(req.ConnString) == "" {
+	connString, opts, err := splitConnectionFlags(strings.TrimSpace(req.ConnString))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.ReadOnly = opts.ReadOnly || req.ReadOnly
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil || timeout < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout %q", req.Timeout))
+			return
+		}
+		opts.Timeout = timeout
+	}
+	if req.MaxRows < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("maxRows must not be negative"))
+		return
+	}
+	if req.MaxRows > 0 {
+		opts.MaxRows = req.MaxRows
+	}
+	req.ConnString = connString
+	if req.ConnString == "" {
 		writeError(w, http.StatusBadRequest, errors.New("connString is required"))
 		return
 	}
@@ -67,7 +183,7 @@ func (api *API) addConnection(w http.ResponseWriter, r *http.Request) {
 	if name == "" {
 		name = fmt.Sprintf("db%d", len(api.connections.List())+1)
 	}
-	if err := api.connections.Add(r.Context(), name, req.ConnString); err != nil {
+	if err := api.connections.AddWithOptions(r.Context(), name, req.ConnString, opts); err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, ErrConnectionExists) {
 			status = http.StatusConflict
@@ -75,13 +191,49 @@ func (api *API) addConnection(w http.ResponseWriter, r *http.Request) {
 		writeError(w, status, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]any{
-		"connection": ConnectionInfo{
-			Name:       name,
-			ConnString: req.ConnString,
-			Default:    api.connections.Default() == name,
-		},
-	})
+	info, _ := api.connections.Info(name)
+	writeJSON(w, http.StatusCreated, map[string]any{"connection": info})
+}
+
+// listDatabases lists the databases on the server of a PostgreSQL connection, with sizes.
+// curl: curl -X GET http://localhost:3000/api/connections/prod/databases
+func (api *API) listDatabases(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	pg, ok := db.(*postgresql.Postgres)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %s", ErrNotPostgres, api.connectionName(r)))
+		return
+	}
+	databases, err := pg.Databases(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"databases": databases})
+}
+
+// openDatabase opens another database on the same server as a derived connection named
+// "{name}.{database}" that reuses the connection's host, credentials and options.
+// curl: curl -X POST http://localhost:3000/api/connections/prod/databases/analytics
+func (api *API) openDatabase(w http.ResponseWriter, r *http.Request) {
+	conn, err := api.connections.AddDerived(r.Context(), r.PathValue("name"), r.PathValue("database"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrConnectionMiss):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrNotPostgres):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrConnectionExists):
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"connection": conn})
 }
 
 // createTable creates a new table with the given columns.
@@ -121,10 +273,17 @@ func (api *API) createTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.CreateTable(ctx, req.Name, req.Columns, req.IfNotExist)
+		}, nil)
+		return
+	}
 	if err := db.CreateTable(r.Context(), req.Name, req.Columns, req.IfNotExist); err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
+	api.logChange(r, AuditEntry{Action: "createTable", Table: req.Name, After: req.Columns})
 	writeJSON(w, http.StatusCreated, map[string]any{"status": "ok"})
 }
 
@@ -143,6 +302,21 @@ func (api *API) listTables(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"tables": tables})
 }
 
+// listSchemas returns the schemas of the database; pass one as ?schema= to the table routes.
+// curl: curl -X GET "http://localhost:3000/api/schemas?db=db1"
+func (api *API) listSchemas(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	schemas, err := db.Schemas(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"schemas": schemas})
+}
+
 // getColumns returns column definitions for a table.
 // curl: curl -X GET "http://localhost:3000/api/tables/users/columns?db=db1"
 func (api *API) getColumns(w http.ResponseWriter, r *http.Request) {
@@ -175,13 +349,82 @@ func (api *API) addColumn(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.AddColumn(ctx, table, req)
+		}, addColumnWarnings(db, table, req))
+		return
+	}
 	if err := db.AddColumn(r.Context(), table, req); err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
+	api.logChange(r, AuditEntry{Action: "addColumn", Table: table, After: req})
 	writeJSON(w, http.StatusCreated, map[string]any{"status": "ok"})
 }
 
+// createIndex adds an index to a table; the name defaults to "<table>_<columns>_idx".
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"columns":["email"],"unique":true}' "http://localhost:3000/api/tables/users/indexes?db=db1"
+func (api *API) createIndex(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	var req database.IndexDef
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.CreateIndex(ctx, table, req)
+		}, nil)
+		return
+	}
+	if err := db.CreateIndex(r.Context(), table, req); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	api.logChange(r, AuditEntry{Action: "createIndex", Table: table, After: req})
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "ok", "name": req.IndexName(table)})
+}
+
+// renameColumn renames a column and reports the views and triggers that reference it.
+//
+//	curl: curl -X PATCH -H "Content-Type: application/json" \
+//	  -d '{"name":"full_name"}' \
+//	  "http://localhost:3000/api/tables/users/columns/name?db=db1"
+func (api *API) renameColumn(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	column := r.PathValue("column")
+	newName, ok := decodeRename(w, r)
+	if !ok {
+		return
+	}
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.RenameColumn(ctx, table, column, newName)
+		}, dependentWarnings(db, table, column))
+		return
+	}
+	dependents, err := db.Dependents(r.Context(), table, column)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := db.RenameColumn(r.Context(), table, column, newName); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	api.logChange(r, AuditEntry{Action: "renameColumn", Table: table, Before: column, After: newName})
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "dependents": dependents})
+}
+
 // dropColumn removes a column from an existing table (requires SQLite 3.35+).
 // curl: curl -X DELETE "http://localhost:3000/api/tables/memberships/columns/notes?db=db1"
 func (api *API) dropColumn(w http.ResponseWriter, r *http.Request) {
@@ -191,10 +434,49 @@ func (api *API) dropColumn(w http.ResponseWriter, r *http.Request) {
 	}
 	table := r.PathValue("table")
 	column := r.PathValue("column")
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.DropColumn(ctx, table, column)
+		}, dropColumnWarnings(db, table, column))
+		return
+	}
 	if err := db.DropColumn(r.Context(), table, column); err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
+	api.logChange(r, AuditEntry{Action: "dropColumn", Table: table, Before: column})
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// alterColumn replaces the definition of an existing column (type, nullability, default, primary key, foreign keys).
+// SQLite tables are rebuilt transparently since ALTER TABLE cannot change these properties there.
+//
+//	curl: curl -X PUT -H "Content-Type: application/json" \
+//	  -d '{"name":"age","type":"INTEGER","notNull":true,"default":"0"}' \
+//	  "http://localhost:3000/api/tables/users/columns/age?db=db1"
+func (api *API) alterColumn(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	column := r.PathValue("column")
+	var req database.ColumnDef
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.AlterColumn(ctx, table, column, req)
+		}, alterColumnWarnings(db, table, column, req))
+		return
+	}
+	if err := db.AlterColumn(r.Context(), table, column, req); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	api.logChange(r, AuditEntry{Action: "alterColumn", Table: table, Before: column, After: req})
 	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 }
 
@@ -238,7 +520,8 @@ func (api *API) getRows(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"rows": rows, "total": total})
 }
 
-// insertRow inserts a JSON row into the given table.
+// insertRow inserts a JSON row into the given table and responds with the stored row,
+// including generated keys and defaults.
 // curl: curl -X POST -H "Content-Type: application/json" -d '{"name":"alice","age":30}' "http://localhost:3000/api/tables/users/rows?db=db1"
 func (api *API) insertRow(w http.ResponseWriter, r *http.Request) {
 	db, ok := api.useDB(w, r)
@@ -251,14 +534,21 @@ func (api *API) insertRow(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	if err := db.Insert(r.Context(), table, row); err != nil {
+	inserted, err := db.InsertReturning(r.Context(), table, row)
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]any{"status": "ok"})
+	key := primaryKeyOf(r.Context(), db, table, inserted)
+	if key != nil {
+		api.recordChange(r, rowChange{Action: "insert", Table: table, AfterKey: key, After: inserted})
+	}
+	api.logChange(r, AuditEntry{Action: "insert", Table: table, Key: key, After: inserted})
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "ok", "row": inserted})
 }
 
 // updateRow updates a row by primary key column/value (supports composite keys).
+// The edit can be reverted with POST /api/undo.
 //
 //	curl: curl -X PUT -H "Content-Type: application/json" \
 //	  -d '{"role":"admin"}' \
@@ -280,10 +570,17 @@ func (api *API) updateRow(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	before := rowImage(r.Context(), db, table, key)
 	if err := db.Update(r.Context(), table, key, row); err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
+	newKey := updatedKey(key, row)
+	after := rowImage(r.Context(), db, table, newKey)
+	if before != nil && after != nil {
+		api.recordChange(r, rowChange{Action: "update", Table: table, BeforeKey: key, AfterKey: newKey, Before: before, After: after})
+	}
+	api.logChange(r, AuditEntry{Action: "update", Table: table, Key: key, Before: before, After: after})
 	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 }
 
@@ -301,15 +598,92 @@ func (api *API) deleteRow(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	before := rowImage(r.Context(), db, table, key)
 	if err := db.Delete(r.Context(), table, key); err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
+	if before != nil {
+		api.recordChange(r, rowChange{Action: "delete", Table: table, BeforeKey: key, Before: before})
+	}
+	api.logChange(r, AuditEntry{Action: "delete", Table: table, Key: key, Before: before})
 	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 }
 
+// alterTable reshapes a table to match the given column list. Columns are matched by name,
+// missing ones are dropped and new ones are added.
+//
+//	curl: curl -X PUT -H "Content-Type: application/json" \
+//	  -d '{"columns":[{"name":"id","type":"INTEGER","primaryKey":true},{"name":"name","type":"TEXT","notNull":true}]}' \
+//	  "http://localhost:3000/api/tables/users?db=db1"
+func (api *API) alterTable(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	var req struct {
+		Columns []database.ColumnDef `json:"columns"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Columns) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("at least one column is required"))
+		return
+	}
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.AlterTable(ctx, table, req.Columns)
+		}, alterTableWarnings(db, table, req.Columns))
+		return
+	}
+	if err := db.AlterTable(r.Context(), table, req.Columns); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	api.logChange(r, AuditEntry{Action: "alterTable", Table: table, After: req.Columns})
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// renameTable renames a table and reports the views and triggers that reference it.
+//
+//	curl: curl -X PATCH -H "Content-Type: application/json" \
+//	  -d '{"name":"accounts"}' \
+//	  "http://localhost:3000/api/tables/users?db=db1"
+func (api *API) renameTable(w http.ResponseWriter, r *http.Request) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	table := r.PathValue("table")
+	newName, ok := decodeRename(w, r)
+	if !ok {
+		return
+	}
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.RenameTable(ctx, table, newName)
+		}, dependentWarnings(db, table, ""))
+		return
+	}
+	dependents, err := db.Dependents(r.Context(), table, "")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := db.RenameTable(r.Context(), table, newName); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	api.logChange(r, AuditEntry{Action: "renameTable", Table: table, Before: table, After: newName})
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "dependents": dependents})
+}
+
 // dropTable deletes an entire table.
 // curl: curl -X DELETE "http://localhost:3000/api/tables/memberships?db=db1"
+// preview: curl -X DELETE "http://localhost:3000/api/tables/memberships?db=db1&dryRun=true"
 func (api *API) dropTable(w http.ResponseWriter, r *http.Request) {
 	db, ok := api.useDB(w, r)
 	if !ok {
@@ -317,37 +691,190 @@ func (api *API) dropTable(w http.ResponseWriter, r *http.Request) {
 	}
 	table := r.PathValue("table")
 	ifExists := r.URL.Query().Get("ifExists") != "false"
+	if isDryRun(r) {
+		preview(w, r, func(ctx context.Context) error {
+			return db.DropTable(ctx, table, ifExists)
+		}, dropTableWarnings(db, table))
+		return
+	}
 	if err := db.DropTable(r.Context(), table, ifExists); err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
+	api.logChange(r, AuditEntry{Action: "dropTable", Table: table})
 	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
 }
 
-// query executes a SELECT-style statement and returns rows.
-// curl: curl -X POST -H "Content-Type: application/json" -d '{"query":"SELECT * FROM users WHERE id = ?","args":[1]}' "http://localhost:3000/api/query?db=db1"
+// query executes a SELECT-style statement and returns rows. Arguments are positional
+// ("args", with the driver's placeholders) or named ("params", with :name or @name
+// placeholders on any driver). While it runs it is listed by GET /api/queries and can be
+// cancelled with DELETE /api/queries/{id}. The connection's timeout and row limit apply
+// unless "timeout"/"maxRows" override them; rows beyond the limit are dropped and
+// "truncated" is set. "stats" reports the duration, time to first row,
+// row count and encoded size. Statements that may write, RETURNING ones included, need
+// admin access like /api/exec; for everyone else queries run read-only.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"query":"SELECT * FROM users WHERE id = :id","params":{"id":1},"maxRows":100}' "http://localhost:3000/api/query?db=db1"
 func (api *API) query(w http.ResponseWriter, r *http.Request) {
 	db, ok := api.useDB(w, r)
 	if !ok {
 		return
 	}
 	var req struct {
-		Query string `json:"query"`
-		Args  []any  `json:"args"`
+		Query   string         `json:"query"`
+		Args    []any          `json:"args"`
+		Params  map[string]any `json:"params"`
+		Timeout string         `json:"timeout"`
+		MaxRows int            `json:"maxRows"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	rows, err := db.Query(r.Context(), req.Query, req.Args...)
+	statement, args, err := bindParams(db, req.Query, req.Args, req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := api.limitsFor(r, req.Timeout, req.MaxRows)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"rows": rows})
+	result, ok := api.runQuery(w, r, db, statement, args, limits)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"queryId":   result.ID,
+		"rows":      json.RawMessage(result.Encoded),
+		"truncated": result.Truncated,
+		"stats":     result.Stats,
+	})
+}
+
+// queryResult is a statement run by runQuery. Encoded holds Rows as JSON; Columns lists
+// the result columns in order.
+type queryResult struct {
+	ID        string
+	Columns   []string
+	Rows      []database.Row
+	Encoded   []byte
+	Truncated bool
+	Stats     statementStats
+}
+
+// runQuery runs statement on db under limits, tracked, measured and recorded like every
+// /api/query call. Errors are written to w, in which case ok is false.
+func (api *API) runQuery(w http.ResponseWriter, r *http.Request, db database.Database, statement string, args []any, limits statementLimits) (result queryResult, ok bool) {
+	result, err := api.queryStatement(r, db, statement, args, limits)
+	if err != nil {
+		writeError(w, statementStatus(err), err)
+		return result, false
+	}
+	return result, true
+}
+
+// queryStatement runs statement for runQuery. Cancelled statements and those running into
+// their timeout fail with ErrQueryCancelled and ErrStatementTimeout. On read-only
+// connections, and for requests carrying database.WithReadOnly, statements that may write
+// fail with ErrConnectionReadOnly; for users without admin access they fail with
+// ErrForbidden. In both cases the rest run read-only, which the driver enforces for
+// statements the keyword check lets through, such as SELECT calling a function that writes.
+// Statements that write, such as data changes with RETURNING, are audited like /api/exec
+// ones.
+func (api *API) queryStatement(r *http.Request, db database.Database, statement string, args []any, limits statementLimits) (queryResult, error) {
+	name := api.connectionName(r)
+	writes := (database.Statement{SQL: statement}).Writes()
+	readOnly := database.ReadOnlyFromContext(r.Context()) || api.connections.ReadOnly(name)
+	if readOnly && writes {
+		return queryResult{}, fmt.Errorf("%w: %s", ErrConnectionReadOnly, name)
+	}
+	canWrite := api.canWrite(r)
+	if !canWrite && writes {
+		return queryResult{}, fmt.Errorf("%w: %s access to %s required to change data", ErrForbidden, RoleAdmin, name)
+	}
+	ctx, running, finish := api.trackQuery(r, "query", statement)
+	if readOnly || !canWrite {
+		ctx = database.WithReadOnly(ctx)
+	}
+	ctx, rowLimit, cancel := withLimits(ctx, limits)
+	defer cancel()
+	ctx, measure := startMeasurement(ctx)
+	rows, err := db.Query(ctx, statement, args...)
+	var encoded []byte
+	if err == nil {
+		encoded, err = json.Marshal(rows)
+	}
+	stats := measure.stats(int64(len(rows)), len(encoded))
+	api.noteStatement(r, "query", statement, args, stats, err)
+	result := queryResult{ID: running.ID, Stats: stats}
+	if cancelled := finish(); cancelled && err != nil {
+		return result, fmt.Errorf("%w: %s", ErrQueryCancelled, running.ID)
+	}
+	if err != nil {
+		return result, statementError(ctx, err, limits)
+	}
+	if writes {
+		api.logChange(r, AuditEntry{Action: "exec", Statement: statement, Args: args})
+	}
+	result.Columns = measure.exec.Columns
+	result.Rows = rows
+	result.Encoded = encoded
+	result.Truncated = rowLimit.Truncated
+	return result, nil
 }
 
-// exec executes a non-query statement and returns metadata.
+// execResult is a statement run by execStatement.
+type execResult struct {
+	ID           string
+	RowsAffected int64
+	LastInsertID int64
+	Stats        statementStats
+}
+
+// execStatement runs a non-query statement on db under limits, tracked, measured, recorded
+// and audited like every /api/exec call. Errors are those of queryStatement.
+func (api *API) execStatement(r *http.Request, db database.Database, statement string, args []any, limits statementLimits) (execResult, error) {
+	ctx, running, finish := api.trackQuery(r, "exec", statement)
+	ctx, _, cancel := withLimits(ctx, limits)
+	defer cancel()
+	ctx, measure := startMeasurement(ctx)
+	res, err := db.Exec(ctx, statement, args...)
+	var affected int64
+	if err == nil && res != nil {
+		affected, _ = res.RowsAffected()
+	}
+	stats := measure.stats(affected, 0)
+	api.noteStatement(r, "exec", statement, args, stats, err)
+	result := execResult{ID: running.ID, RowsAffected: affected, Stats: stats}
+	if cancelled := finish(); cancelled && err != nil {
+		return result, fmt.Errorf("%w: %s", ErrQueryCancelled, running.ID)
+	}
+	if err != nil {
+		return result, statementError(ctx, err, limits)
+	}
+	api.logChange(r, AuditEntry{Action: "exec", Statement: statement, Args: args})
+	if res != nil {
+		result.LastInsertID, _ = res.LastInsertId()
+	}
+	return result, nil
+}
+
+// bindParams returns the statement and positional arguments of a /api/query or /api/exec
+// body, rewriting its :name and @name placeholders for db when params are given.
+func bindParams(db database.Database, query string, args []any, params map[string]any) (string, []any, error) {
+	if params == nil {
+		return query, args, nil
+	}
+	if len(args) > 0 {
+		return "", nil, errors.New("pass either args or params, not both")
+	}
+	return database.BindNamed(query, params, db.Placeholder)
+}
+
+// exec executes a non-query statement and returns metadata, "stats" included (with the
+// command tag on PostgreSQL). Like query, it takes "args" or "params", can be cancelled while
+// it runs and is bound by the connection's timeout or "timeout".
 // curl: curl -X POST -H "Content-Type: application/json" -d '{"query":"UPDATE users SET age = ? WHERE id = ?","args":[32,1]}' "http://localhost:3000/api/exec?db=db1"
 func (api *API) exec(w http.ResponseWriter, r *http.Request) {
 	db, ok := api.useDB(w, r)
@@ -355,29 +882,35 @@ func (api *API) exec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Query string `json:"query"`
-		Args  []any  `json:"args"`
+		Query   string         `json:"query"`
+		Args    []any          `json:"args"`
+		Params  map[string]any `json:"params"`
+		Timeout string         `json:"timeout"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	res, err := db.Exec(r.Context(), req.Query, req.Args...)
+	statement, args, err := bindParams(db, req.Query, req.Args, req.Params)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	var (
-		lastInsert int64
-		affected   int64
-	)
-	if res != nil {
-		lastInsert, _ = res.LastInsertId()
-		affected, _ = res.RowsAffected()
+	limits, err := api.limitsFor(r, req.Timeout, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := api.execStatement(r, db, statement, args, limits)
+	if err != nil {
+		writeError(w, statementStatus(err), err)
+		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"lastInsertId": lastInsert,
-		"rowsAffected": affected,
+		"queryId":      result.ID,
+		"lastInsertId": result.LastInsertID,
+		"rowsAffected": result.RowsAffected,
+		"stats":        result.Stats,
 	})
 }
 
@@ -390,6 +923,23 @@ func decodeRow(r *http.Request) (database.Row, error) {
 	return row, nil
 }
 
+// decodeRename reads the {"name": "..."} body shared by the rename endpoints.
+func decodeRename(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("new name is required"))
+		return "", false
+	}
+	return name, true
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -419,9 +969,19 @@ func queryInt(r *http.Request, key string) int {
 	return i
 }
 
+// connectionName returns the connection selected by ?db=, falling back to the default one.
+func (api *API) connectionName(r *http.Request) string {
+	if name := r.PathValue("name"); name != "" {
+		return name
+	}
+	if name := r.URL.Query().Get("db"); name != "" {
+		return name
+	}
+	return api.connections.Default()
+}
+
 func (api *API) useDB(w http.ResponseWriter, r *http.Request) (database.Database, bool) {
-	dbName := r.URL.Query().Get("db")
-	db, err := api.connections.Get(dbName)
+	db, err := api.connections.Get(api.connectionName(r))
 	if err != nil {
 		status := http.StatusBadRequest
 		if errors.Is(err, ErrConnectionMiss) {
@@ -0,0 +1,558 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+var (
+	ErrSavedQueryNotFound = errors.New("saved query not found")
+	ErrInvalidParam       = errors.New("invalid parameter")
+)
+
+// Parameter types of a saved query. Dates are passed to the driver as YYYY-MM-DD text.
+const (
+	paramText    = "text"
+	paramInteger = "integer"
+	paramNumber  = "number"
+	paramBoolean = "boolean"
+	paramDate    = "date"
+)
+
+var paramName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SavedQuery is a named SQL snippet whose :name or @name placeholders are declared as
+// typed parameters. An empty Connection runs it on the default connection.
+type SavedQuery struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Connection  string       `json:"connection,omitempty"`
+	SQL         string       `json:"sql"`
+	Params      []QueryParam `json:"params,omitempty"`
+	Slug        string       `json:"slug,omitempty"`      // Published at /q/{slug} when set.
+	Protected   bool         `json:"protected,omitempty"` // The published endpoint requires an API key.
+	CreatedBy   string       `json:"createdBy,omitempty"`
+	Created     time.Time    `json:"created"`
+	Updated     time.Time    `json:"updated"`
+
+	apiKeyHash string
+}
+
+// QueryParam declares a named placeholder. Missing values fall back to Default, then to
+// NULL unless the parameter is required.
+type QueryParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // text (default), integer, number, boolean or date
+	Required    bool   `json:"required,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SavedQueryFilter narrows ListSavedQueries results. Search matches the name, description
+// and SQL.
+type SavedQueryFilter struct {
+	Tag    string
+	Search string
+}
+
+// validate normalises q and checks that its parameters are well formed and match the
+// placeholders in its SQL.
+func (q *SavedQuery) validate() error {
+	q.Name = strings.TrimSpace(q.Name)
+	if q.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(q.SQL) == "" {
+		return errors.New("sql is required")
+	}
+	tags := q.Tags[:0]
+	for _, tag := range q.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	q.Tags = tags
+
+	declared := map[string]bool{}
+	for i := range q.Params {
+		p := &q.Params[i]
+		if !paramName.MatchString(p.Name) {
+			return fmt.Errorf("%w name %q", ErrInvalidParam, p.Name)
+		}
+		if declared[p.Name] {
+			return fmt.Errorf("parameter :%s is declared twice", p.Name)
+		}
+		declared[p.Name] = true
+		switch p.Type {
+		case "":
+			p.Type = paramText
+		case paramText, paramInteger, paramNumber, paramBoolean, paramDate:
+		default:
+			return fmt.Errorf("%w type %q for :%s", ErrInvalidParam, p.Type, p.Name)
+		}
+		if p.Default != nil {
+			if _, err := p.convert(p.Default); err != nil {
+				return fmt.Errorf("default: %w", err)
+			}
+		}
+	}
+	for _, name := range database.NamedParams(q.SQL) {
+		if !declared[name] {
+			return fmt.Errorf("parameter :%s is used but not declared", name)
+		}
+	}
+	return nil
+}
+
+// bind checks values against the declared parameters and returns q's SQL with driver
+// placeholders and the matching arguments.
+func (q SavedQuery) bind(values map[string]any, placeholder func(int) string) (string, []any, error) {
+	params := make(map[string]any, len(q.Params))
+	for _, p := range q.Params {
+		raw, ok := values[p.Name]
+		if !ok || raw == nil {
+			raw = p.Default
+		}
+		if raw == nil {
+			if p.Required {
+				return "", nil, fmt.Errorf("%w :%s", database.ErrMissingParam, p.Name)
+			}
+			params[p.Name] = nil
+			continue
+		}
+		value, err := p.convert(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		params[p.Name] = value
+	}
+	for name := range values {
+		if _, ok := params[name]; !ok {
+			return "", nil, fmt.Errorf("%w: unknown parameter :%s", ErrInvalidParam, name)
+		}
+	}
+	return database.BindNamed(q.SQL, params, placeholder)
+}
+
+// convert returns raw as a value of the parameter's type. Strings are parsed, so values
+// from JSON bodies and URL query strings are handled alike.
+func (p QueryParam) convert(raw any) (any, error) {
+	invalid := func() error {
+		return fmt.Errorf("%w :%s: %v is not a valid %s", ErrInvalidParam, p.Name, raw, p.Type)
+	}
+	s, isString := raw.(string)
+	switch p.Type {
+	case paramText, "":
+		if !isString {
+			return nil, invalid()
+		}
+		return s, nil
+	case paramInteger:
+		switch v := raw.(type) {
+		case json.Number:
+			s, isString = v.String(), true
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		case int64:
+			return v, nil
+		}
+		if i, err := strconv.ParseInt(s, 10, 64); isString && err == nil {
+			return i, nil
+		}
+	case paramNumber:
+		switch v := raw.(type) {
+		case json.Number:
+			s, isString = v.String(), true
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); isString && err == nil {
+			return f, nil
+		}
+	case paramBoolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+		if b, err := strconv.ParseBool(s); isString && err == nil {
+			return b, nil
+		}
+	case paramDate:
+		if _, err := time.Parse(time.DateOnly, s); isString && err == nil {
+			return s, nil
+		}
+	}
+	return nil, invalid()
+}
+
+func (m *Metadata) ListSavedQueries(ctx context.Context, filter SavedQueryFilter) ([]SavedQuery, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if filter.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)")
+		args = append(args, filter.Tag)
+	}
+	if filter.Search != "" {
+		where = append(where, "(name LIKE ? OR description LIKE ? OR sql LIKE ?)")
+		pattern := "%" + filter.Search + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+	query := "SELECT * FROM saved_queries"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := m.db.Query(ctx, query+" ORDER BY name, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]SavedQuery, 0, len(rows))
+	for _, row := range rows {
+		q, err := decodeSavedQueryRow(row)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+func (m *Metadata) GetSavedQuery(ctx context.Context, id int64) (SavedQuery, error) {
+	rows, err := m.db.Find(ctx, "saved_queries", database.Key{"id": id}, 1, 0)
+	if err != nil {
+		return SavedQuery{}, err
+	}
+	if len(rows) == 0 {
+		return SavedQuery{}, ErrSavedQueryNotFound
+	}
+	return decodeSavedQueryRow(rows[0])
+}
+
+// CreateSavedQuery stores q, unpublished, and returns it with its ID and timestamps set.
+func (m *Metadata) CreateSavedQuery(ctx context.Context, q SavedQuery) (SavedQuery, error) {
+	q.Created = time.Now()
+	q.Updated = q.Created
+	q.Slug, q.Protected, q.apiKeyHash = "", false, ""
+	row, err := encodeSavedQuery(q)
+	if err != nil {
+		return q, err
+	}
+	row["created_by"] = nullString(q.CreatedBy)
+	row["created"] = q.Created.UTC().Format(auditTimeFormat)
+	stored, err := m.db.InsertReturning(ctx, "saved_queries", row)
+	if err != nil {
+		return q, err
+	}
+	q.ID, _ = stored["id"].(int64)
+	return q, nil
+}
+
+// UpdateSavedQuery replaces the stored query q.ID, keeping its creator, creation time and
+// publication.
+func (m *Metadata) UpdateSavedQuery(ctx context.Context, q SavedQuery) (SavedQuery, error) {
+	existing, err := m.GetSavedQuery(ctx, q.ID)
+	if err != nil {
+		return q, err
+	}
+	q.CreatedBy, q.Created, q.Updated = existing.CreatedBy, existing.Created, time.Now()
+	q.Slug, q.Protected, q.apiKeyHash = existing.Slug, existing.Protected, existing.apiKeyHash
+	row, err := encodeSavedQuery(q)
+	if err != nil {
+		return q, err
+	}
+	return q, m.db.Update(ctx, "saved_queries", database.Key{"id": q.ID}, row)
+}
+
+func (m *Metadata) DeleteSavedQuery(ctx context.Context, id int64) error {
+	res, err := m.db.Exec(ctx, "DELETE FROM saved_queries WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSavedQueryNotFound
+	}
+	return nil
+}
+
+func encodeSavedQuery(q SavedQuery) (database.Row, error) {
+	row := database.Row{
+		"name":        q.Name,
+		"description": nullString(q.Description),
+		"connection":  nullString(q.Connection),
+		"sql":         q.SQL,
+		"updated":     q.Updated.UTC().Format(auditTimeFormat),
+	}
+	for column, value := range map[string]any{"tags": q.Tags, "params": q.Params} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", column, err)
+		}
+		row[column] = string(encoded)
+	}
+	return row, nil
+}
+
+func decodeSavedQueryRow(row database.Row) (SavedQuery, error) {
+	text := func(column string) string {
+		s, _ := row[column].(string)
+		return s
+	}
+	q := SavedQuery{
+		Name:        text("name"),
+		Description: text("description"),
+		Connection:  text("connection"),
+		SQL:         text("sql"),
+		Slug:        text("slug"),
+		CreatedBy:   text("created_by"),
+		apiKeyHash:  text("api_key_hash"),
+	}
+	q.Protected = q.apiKeyHash != ""
+	q.ID, _ = row["id"].(int64)
+	for column, target := range map[string]*time.Time{"created": &q.Created, "updated": &q.Updated} {
+		t, err := time.Parse(auditTimeFormat, text(column))
+		if err != nil {
+			return q, err
+		}
+		*target = t
+	}
+	for column, target := range map[string]any{"tags": &q.Tags, "params": &q.Params} {
+		if raw := text(column); raw != "" {
+			if err := json.Unmarshal([]byte(raw), target); err != nil {
+				return q, fmt.Errorf("decode %s of saved query %d: %w", column, q.ID, err)
+			}
+		}
+	}
+	return q, nil
+}
+
+// onConnection returns r with ?db= set to name, so the connection-scoped helpers (roles,
+// limits, query tracking, history) apply to that connection.
+func onConnection(r *http.Request, name string) *http.Request {
+	if name == "" {
+		return r
+	}
+	r = r.Clone(r.Context())
+	q := r.URL.Query()
+	q.Set("db", name)
+	r.URL.RawQuery = q.Encode()
+	return r
+}
+
+// savedQueryAccess returns ErrForbidden unless the user of r has required on the
+// connection of q.
+func (api *API) savedQueryAccess(r *http.Request, q SavedQuery, required Role) error {
+	if api.access == nil {
+		return nil
+	}
+	name := api.connectionName(onConnection(r, q.Connection))
+	if !api.roleOn(Principal(r.Context()), name).Allows(required) {
+		return fmt.Errorf("%w: %s access to %s required", ErrForbidden, required, name)
+	}
+	return nil
+}
+
+// loadSavedQuery returns the saved query named by the {id} path value if the user of r has
+// required on its connection, writing the error to w otherwise.
+func (api *API) loadSavedQuery(w http.ResponseWriter, r *http.Request, required Role) (SavedQuery, bool) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return SavedQuery{}, false
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid saved query id: %w", err))
+		return SavedQuery{}, false
+	}
+	q, err := api.metadata.GetSavedQuery(r.Context(), id)
+	if errors.Is(err, ErrSavedQueryNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return q, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return q, false
+	}
+	if err := api.savedQueryAccess(r, q, required); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return q, false
+	}
+	return q, true
+}
+
+// decodeSavedQuery reads a saved query from the body of r and checks that the user of r
+// administers its connection, writing the error to w otherwise.
+func (api *API) decodeSavedQuery(w http.ResponseWriter, r *http.Request) (SavedQuery, bool) {
+	var q SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return q, false
+	}
+	if err := q.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return q, false
+	}
+	if q.Connection != "" {
+		if _, err := api.connections.Get(q.Connection); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return q, false
+		}
+	}
+	if err := api.savedQueryAccess(r, q, RoleAdmin); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return q, false
+	}
+	return q, true
+}
+
+// listSavedQueries returns the saved queries on connections the user may view, sorted by
+// name, optionally filtered by ?tag= and a ?q= substring of the name, description or SQL.
+// curl: curl -X GET "http://localhost:3000/api/saved-queries?tag=support&q=orders"
+func (api *API) listSavedQueries(w http.ResponseWriter, r *http.Request) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return
+	}
+	q := r.URL.Query()
+	all, err := api.metadata.ListSavedQueries(r.Context(), SavedQueryFilter{Tag: q.Get("tag"), Search: q.Get("q")})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	queries := []SavedQuery{}
+	for _, saved := range all {
+		if api.savedQueryAccess(r, saved, RoleViewer) == nil {
+			queries = append(queries, saved)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"queries": queries})
+}
+
+// createSavedQuery stores a query for its connection's admins to vet and its viewers to run.
+// Every :name placeholder in the SQL must be declared in params.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"name":"Orders by user","tags":["support"],"connection":"main","sql":"SELECT * FROM orders WHERE user_id = :user_id","params":[{"name":"user_id","type":"integer","required":true}]}' "http://localhost:3000/api/saved-queries"
+func (api *API) createSavedQuery(w http.ResponseWriter, r *http.Request) {
+	if api.metadata == nil {
+		writeError(w, http.StatusNotFound, ErrMetadataDisabled)
+		return
+	}
+	q, ok := api.decodeSavedQuery(w, r)
+	if !ok {
+		return
+	}
+	q.CreatedBy = Principal(r.Context())
+	q, err := api.metadata.CreateSavedQuery(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, q)
+}
+
+// getSavedQuery returns one saved query.
+// curl: curl -X GET "http://localhost:3000/api/saved-queries/1"
+func (api *API) getSavedQuery(w http.ResponseWriter, r *http.Request) {
+	q, ok := api.loadSavedQuery(w, r, RoleViewer)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+// updateSavedQuery replaces a saved query. Admin access is required on both its current and
+// its new connection.
+// curl: curl -X PUT -H "Content-Type: application/json" -d '{"name":"Orders by user","sql":"SELECT * FROM orders WHERE user_id = :user_id ORDER BY id DESC","params":[{"name":"user_id","type":"integer","required":true}]}' "http://localhost:3000/api/saved-queries/1"
+func (api *API) updateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	existing, ok := api.loadSavedQuery(w, r, RoleAdmin)
+	if !ok {
+		return
+	}
+	q, ok := api.decodeSavedQuery(w, r)
+	if !ok {
+		return
+	}
+	q.ID = existing.ID
+	q, err := api.metadata.UpdateSavedQuery(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+// deleteSavedQuery removes a saved query.
+// curl: curl -X DELETE "http://localhost:3000/api/saved-queries/1"
+func (api *API) deleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	q, ok := api.loadSavedQuery(w, r, RoleAdmin)
+	if !ok {
+		return
+	}
+	if err := api.metadata.DeleteSavedQuery(r.Context(), q.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// runSavedQuery runs a saved query on its connection. Parameter values are checked against
+// their declared types and bound as driver arguments, never spliced into the SQL. Viewers
+// of the connection may run it; the response matches /api/query.
+// curl: curl -X POST -H "Content-Type: application/json" -d '{"params":{"user_id":42},"maxRows":100}' "http://localhost:3000/api/saved-queries/1/run"
+func (api *API) runSavedQuery(w http.ResponseWriter, r *http.Request) {
+	saved, ok := api.loadSavedQuery(w, r, RoleViewer)
+	if !ok {
+		return
+	}
+	var req struct {
+		Params  map[string]any `json:"params"`
+		Timeout string         `json:"timeout"`
+		MaxRows int            `json:"maxRows"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	r = onConnection(r, saved.Connection)
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	statement, args, err := saved.bind(req.Params, db.Placeholder)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := api.limitsFor(r, req.Timeout, req.MaxRows)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, ok := api.runQuery(w, r, db, statement, args, limits)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"queryId":   result.ID,
+		"rows":      json.RawMessage(result.Encoded),
+		"truncated": result.Truncated,
+		"stats":     result.Stats,
+	})
+}
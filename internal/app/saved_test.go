@@ -0,0 +1,81 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunSavedQuery(t *testing.T) {
+	api := newTestAPI(t)
+	api.exec(
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, note TEXT)",
+		"INSERT INTO orders (user_id, note) VALUES (1, 'first'), (1, 'second'), (2, 'other')",
+	)
+	api.useMetadata()
+
+	if rec := api.do(http.MethodPost, "/api/saved-queries", `{"name":"bad","sql":"SELECT :undeclared"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected undeclared parameters to be rejected, got %d %s", rec.Code, rec.Body)
+	}
+	rec := api.do(http.MethodPost, "/api/saved-queries", `{"name":"Orders by user","tags":["support"],"connection":"main",
+		"sql":"SELECT note FROM orders WHERE user_id = :user_id AND note LIKE :pattern ORDER BY id",
+		"params":[{"name":"user_id","type":"integer","required":true},{"name":"pattern","default":"%"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	var saved SavedQuery
+	if err := json.Unmarshal(rec.Body.Bytes(), &saved); err != nil || saved.ID == 0 || saved.Params[1].Type != paramText {
+		t.Fatalf("unexpected saved query %+v (%v)", saved, err)
+	}
+	if rec := api.do(http.MethodGet, "/api/saved-queries?tag=support", ""); !strings.Contains(rec.Body.String(), "Orders by user") {
+		t.Fatalf("expected the query under its tag, got %s", rec.Body)
+	}
+
+	run := func(body string) *httptest.ResponseRecorder {
+		return api.do(http.MethodPost, "/api/saved-queries/1/run", body)
+	}
+	rec = run(`{"params":{"user_id":1}}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rows":[{"note":"first"},{"note":"second"}]`) {
+		t.Fatalf("run: %d %s", rec.Code, rec.Body)
+	}
+	if rec := run(`{"params":{"user_id":"1 OR 1=1"}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a type error, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := run(`{"params":{"user_id":"2","pattern":"x' OR '1'='1"}}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rowCount":0`) {
+		t.Fatalf("expected the pattern to be bound as a value, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := run(`{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a missing parameter error, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := api.do(http.MethodDelete, "/api/saved-queries/1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if rec := api.do(http.MethodGet, "/api/saved-queries/1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestQueryNamedParams(t *testing.T) {
+	api := newTestAPI(t)
+	do := func(target, body string) *httptest.ResponseRecorder {
+		return api.do(http.MethodPost, target, body)
+	}
+
+	do("/api/exec", `{"query":"CREATE TABLE t (id INTEGER PRIMARY KEY, note TEXT)"}`)
+	if rec := do("/api/exec", `{"query":"INSERT INTO t (id, note) VALUES (:id, @note)","params":{"id":1,"note":"a ':id' -- @note"}}`); rec.Code != http.StatusOK {
+		t.Fatalf("exec: %d %s", rec.Code, rec.Body)
+	}
+	rec := do("/api/query", `{"query":"SELECT note, ':id' AS lit FROM t WHERE id = :id AND id = @id -- :ignored","params":{"id":1}}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"lit":":id"`) || !strings.Contains(rec.Body.String(), `"note":"a ':id' -- @note"`) {
+		t.Fatalf("query: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("/api/query", `{"query":"SELECT :id","params":{}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a missing parameter error, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("/api/query", `{"query":"SELECT :id","args":[1],"params":{"id":1}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected args and params to be exclusive, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"sqlite-gui/pkg/database"
+)
+
+const (
+	defaultSlowQueryThreshold = time.Second
+	slowQueryLogSize          = 200 // Entries kept; older ones are overwritten.
+)
+
+// statementStats describe one /api/query or /api/exec call. Bytes is the size of the
+// encoded rows; FirstRowMs is the time until the database returned the first row.
+type statementStats struct {
+	DurationMs float64  `json:"durationMs"`
+	FirstRowMs *float64 `json:"firstRowMs,omitempty"`
+	RowCount   int64    `json:"rowCount"`
+	Bytes      int      `json:"bytes"`
+	CommandTag string   `json:"commandTag,omitempty"` // PostgreSQL only.
+}
+
+// measurement times a statement and collects the driver's stats for it.
+type measurement struct {
+	start time.Time
+	exec  database.ExecStats
+}
+
+func startMeasurement(ctx context.Context) (context.Context, *measurement) {
+	m := &measurement{start: time.Now()}
+	return database.WithStats(ctx, &m.exec), m
+}
+
+func (m *measurement) stats(rowCount int64, bytes int) statementStats {
+	stats := statementStats{
+		DurationMs: milliseconds(time.Since(m.start)),
+		RowCount:   rowCount,
+		Bytes:      bytes,
+		CommandTag: m.exec.CommandTag,
+	}
+	if m.exec.HasFirstRow {
+		firstRow := milliseconds(m.exec.FirstRow)
+		stats.FirstRowMs = &firstRow
+	}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// SlowQuery is a statement that ran for at least the slow-query threshold.
+type SlowQuery struct {
+	Time       time.Time `json:"time"`
+	Connection string    `json:"connection"`
+	Kind       string    `json:"kind"`
+	Statement  string    `json:"statement"`
+	Args       []any     `json:"args,omitempty"`
+	User       string    `json:"user,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+	RowCount   int64     `json:"rowCount"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SlowQueryLog keeps the most recent slow statements in a ring buffer.
+type SlowQueryLog struct {
+	mu        sync.Mutex
+	threshold time.Duration // Zero or less disables the log.
+	entries   []SlowQuery
+	next      int
+}
+
+func NewSlowQueryLog(threshold time.Duration) *SlowQueryLog {
+	return &SlowQueryLog{threshold: threshold}
+}
+
+func (l *SlowQueryLog) Threshold() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.threshold
+}
+
+// Record keeps q when it ran for at least the threshold.
+func (l *SlowQueryLog) Record(q SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.threshold <= 0 || q.DurationMs < milliseconds(l.threshold) {
+		return
+	}
+	if len(l.entries) < slowQueryLogSize {
+		l.entries = append(l.entries, q)
+		return
+	}
+	l.entries[l.next] = q
+	l.next = (l.next + 1) % slowQueryLogSize
+}
+
+// List returns the recorded statements, newest first.
+func (l *SlowQueryLog) List() []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := make([]SlowQuery, 0, len(l.entries))
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		list = append(list, l.entries[(l.next+i)%len(l.entries)])
+	}
+	return list
+}
+
+// noteStatement records a finished /api/query or /api/exec call in the query history and
+// the slow-query log.
+func (api *API) noteStatement(r *http.Request, kind, statement string, args []any, stats statementStats, err error) {
+	q := SlowQuery{
+		Time:       time.Now(),
+		Connection: api.connectionName(r),
+		Kind:       kind,
+		Statement:  statement,
+		Args:       args,
+		User:       Principal(r.Context()),
+		DurationMs: stats.DurationMs,
+		RowCount:   stats.RowCount,
+	}
+	if err != nil {
+		q.Error = err.Error()
+	}
+	api.slowQueries.Record(q)
+	api.recordHistory(r, HistoryEntry{
+		Time:       q.Time,
+		Connection: q.Connection,
+		Kind:       q.Kind,
+		User:       q.User,
+		Statement:  q.Statement,
+		Args:       q.Args,
+		DurationMs: q.DurationMs,
+		RowCount:   q.RowCount,
+		Error:      q.Error,
+	})
+}
+
+// getSlowQueries lists the statements that ran for at least the slow-query threshold
+// (-slow-query-threshold), newest first, optionally limited to one connection with ?db=.
+// curl: curl -X GET "http://localhost:3000/api/slow-queries?db=db1"
+func (api *API) getSlowQueries(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"thresholdMs": milliseconds(api.slowQueries.Threshold()),
+		"queries":     api.recentSlowQueries(r, r.URL.Query().Get("db")),
+	})
+}
+
+// recentSlowQueries returns the slow statements on connection ("" for all) that the
+// user of r may view.
+func (api *API) recentSlowQueries(r *http.Request, connection string) []SlowQuery {
+	user := Principal(r.Context())
+	queries := []SlowQuery{}
+	for _, q := range api.slowQueries.List() {
+		if connection != "" && q.Connection != connection {
+			continue
+		}
+		if api.access != nil && !api.roleOn(user, q.Connection).Allows(RoleViewer) {
+			continue
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryLogKeepsNewest(t *testing.T) {
+	log := NewSlowQueryLog(time.Millisecond)
+	log.Record(SlowQuery{Statement: "fast", DurationMs: 0.5})
+	for i := 0; i < slowQueryLogSize+5; i++ {
+		log.Record(SlowQuery{Statement: "slow", RowCount: int64(i), DurationMs: 2})
+	}
+	list := log.List()
+	if len(list) != slowQueryLogSize || list[0].RowCount != slowQueryLogSize+4 || list[len(list)-1].RowCount != 5 {
+		t.Fatalf("unexpected ring contents: %d entries, newest %d, oldest %d", len(list), list[0].RowCount, list[len(list)-1].RowCount)
+	}
+	off := NewSlowQueryLog(0)
+	off.Record(SlowQuery{DurationMs: 1e6})
+	if len(off.List()) != 0 {
+		t.Fatalf("expected a zero threshold to disable the log")
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	api := newTestAPI(t)
+	api.SetSlowQueryThreshold(time.Nanosecond)
+
+	rec := api.do(http.MethodPost, "/api/query", `{"query":"SELECT 1 AS a UNION ALL SELECT 2"}`)
+	var resp struct {
+		Rows  json.RawMessage `json:"rows"`
+		Stats statementStats  `json:"stats"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if resp.Stats.RowCount != 2 || resp.Stats.Bytes != len(resp.Rows) || resp.Stats.FirstRowMs == nil || resp.Stats.DurationMs <= 0 {
+		t.Fatalf("unexpected stats %+v for rows %s", resp.Stats, resp.Rows)
+	}
+
+	rec = api.do(http.MethodGet, "/api/slow-queries?db=main", "")
+	if !strings.Contains(rec.Body.String(), "SELECT 1 AS a") {
+		t.Fatalf("expected the query in the slow-query log, got %s", rec.Body)
+	}
+}
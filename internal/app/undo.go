@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"sqlite-gui/pkg/database"
+)
+
+const undoDepth = 100 // Changes kept per connection; older ones are forgotten.
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	ErrRowChanged    = errors.New("row has changed since the edit")
+)
+
+// rowChange is a row edit made through the API: the row image at BeforeKey becomes the image
+// at AfterKey. Before is nil for inserts and After is nil for deletes.
+type rowChange struct {
+	Action    string       `json:"action"`
+	Schema    string       `json:"schema,omitempty"`
+	Table     string       `json:"table"`
+	BeforeKey database.Key `json:"beforeKey,omitempty"`
+	AfterKey  database.Key `json:"afterKey,omitempty"`
+	Before    database.Row `json:"before,omitempty"`
+	After     database.Row `json:"after,omitempty"`
+}
+
+func (c rowChange) inverse() rowChange {
+	return rowChange{
+		Action:    c.Action,
+		Schema:    c.Schema,
+		Table:     c.Table,
+		BeforeKey: c.AfterKey,
+		AfterKey:  c.BeforeKey,
+		Before:    c.After,
+		After:     c.Before,
+	}
+}
+
+// apply moves the row from c.Before to c.After, refusing with ErrRowChanged when the
+// current row no longer matches c.Before.
+func (c rowChange) apply(ctx context.Context, db database.Database) error {
+	ctx = database.WithSchema(ctx, c.Schema)
+	if c.Before == nil {
+		rows, err := db.Find(ctx, c.Table, c.AfterKey, 1, 0)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			return fmt.Errorf("%w: %s %v exists again", ErrRowChanged, c.Table, c.AfterKey)
+		}
+		return db.Insert(ctx, c.Table, c.After)
+	}
+
+	rows, err := db.Find(ctx, c.Table, c.BeforeKey, 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 || !reflect.DeepEqual(rows[0], c.Before) {
+		return fmt.Errorf("%w: %s %v", ErrRowChanged, c.Table, c.BeforeKey)
+	}
+	if c.After == nil {
+		return db.Delete(ctx, c.Table, c.BeforeKey)
+	}
+	return db.Update(ctx, c.Table, c.BeforeKey, c.After)
+}
+
+type undoStack struct {
+	undo []rowChange
+	redo []rowChange
+}
+
+// UndoHistory keeps an undo and a redo stack of row edits per connection.
+type UndoHistory struct {
+	mu     sync.Mutex
+	stacks map[string]*undoStack
+}
+
+func NewUndoHistory() *UndoHistory {
+	return &UndoHistory{stacks: make(map[string]*undoStack)}
+}
+
+func (h *UndoHistory) stack(connection string) *undoStack {
+	s, ok := h.stacks[connection]
+	if !ok {
+		s = &undoStack{}
+		h.stacks[connection] = s
+	}
+	return s
+}
+
+// push records a new edit and clears the redo stack.
+func (h *UndoHistory) push(connection string, change rowChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stack(connection)
+	s.undo = append(s.undo, change)
+	if len(s.undo) > undoDepth {
+		s.undo = s.undo[len(s.undo)-undoDepth:]
+	}
+	s.redo = nil
+}
+
+// step applies the inverse of the latest undo entry (or the latest redo entry when redo is
+// set) and moves it to the other stack. An entry whose row has changed since is discarded;
+// after any other error, such as a lost connection, it stays for another try.
+func (h *UndoHistory) step(ctx context.Context, connection string, db database.Database, redo bool) (rowChange, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stack(connection)
+	from, to := &s.undo, &s.redo
+	if redo {
+		from, to = &s.redo, &s.undo
+	}
+	if len(*from) == 0 {
+		if redo {
+			return rowChange{}, ErrNothingToRedo
+		}
+		return rowChange{}, ErrNothingToUndo
+	}
+	change := (*from)[len(*from)-1]
+
+	applied := change
+	if !redo {
+		applied = change.inverse()
+	}
+	if err := applied.apply(ctx, db); err != nil {
+		if errors.Is(err, ErrRowChanged) {
+			*from = (*from)[:len(*from)-1]
+		}
+		return applied, err
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, change)
+	return applied, nil
+}
+
+// recordChange pushes a row edit onto the connection's undo stack.
+func (api *API) recordChange(r *http.Request, change rowChange) {
+	change.Schema = database.SchemaFromContext(r.Context())
+	api.undo.push(api.connectionName(r), change)
+}
+
+// rowImage loads the row at key, returning nil when it cannot be read.
+func rowImage(ctx context.Context, db database.Database, table string, key database.Key) database.Row {
+	rows, err := db.Find(ctx, table, key, 1, 0)
+	if err != nil || len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+// primaryKeyOf returns the key of row using the table's declared primary key columns.
+// It returns nil when the table has none or row lacks a key value.
+func primaryKeyOf(ctx context.Context, db database.Database, table string, row database.Row) database.Key {
+	cols, err := db.Columns(ctx, table)
+	if err != nil {
+		return nil
+	}
+	key := database.Key{}
+	for _, col := range cols {
+		if !col.PrimaryKey {
+			continue
+		}
+		value, ok := row[col.Name]
+		if !ok || value == nil {
+			return nil
+		}
+		key[col.Name] = value
+	}
+	if len(key) == 0 {
+		return nil
+	}
+	return key
+}
+
+// undoChange reverts the latest row edit made through the API on the connection.
+// It answers 409 when the row has been changed since; that edit is then dropped from the stack.
+// curl: curl -X POST "http://localhost:3000/api/undo?db=db1"
+func (api *API) undoChange(w http.ResponseWriter, r *http.Request) {
+	api.stepChange(w, r, false)
+}
+
+// redoChange re-applies the latest undone row edit on the connection.
+// curl: curl -X POST "http://localhost:3000/api/redo?db=db1"
+func (api *API) redoChange(w http.ResponseWriter, r *http.Request) {
+	api.stepChange(w, r, true)
+}
+
+func (api *API) stepChange(w http.ResponseWriter, r *http.Request, redo bool) {
+	db, ok := api.useDB(w, r)
+	if !ok {
+		return
+	}
+	applied, err := api.undo.step(r.Context(), api.connectionName(r), db, redo)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrNothingToUndo), errors.Is(err, ErrNothingToRedo):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrRowChanged):
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	action := "undo"
+	if redo {
+		action = "redo"
+	}
+	key := applied.AfterKey
+	if key == nil {
+		key = applied.BeforeKey
+	}
+	api.logChange(r, AuditEntry{Action: action, Schema: applied.Schema, Table: applied.Table, Key: key, Before: applied.Before, After: applied.After})
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "change": applied})
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestUndoRedoRowEdits(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	api.exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	do := func(method, target, body string) int {
+		rec := api.do(method, target, body)
+		return rec.Code
+	}
+	name := func() any {
+		rows, err := api.db.Query(ctx, "SELECT name FROM users WHERE id = 1")
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return rows[0]["name"]
+	}
+
+	if code := do(http.MethodPost, "/api/tables/users/rows", `{"name":"alice"}`); code != http.StatusCreated {
+		t.Fatalf("insert: %d", code)
+	}
+	if code := do(http.MethodPut, "/api/tables/users/rows/1", `{"name":"alicia"}`); code != http.StatusOK {
+		t.Fatalf("update: %d", code)
+	}
+	if code := do(http.MethodDelete, "/api/tables/users/rows/1", ""); code != http.StatusOK {
+		t.Fatalf("delete: %d", code)
+	}
+
+	steps := []struct {
+		target string
+		want   any
+	}{
+		{"/api/undo", "alicia"}, // restores the deleted row
+		{"/api/undo", "alice"},  // reverts the update
+		{"/api/undo", nil},      // removes the inserted row
+		{"/api/redo", "alice"},
+		{"/api/redo", "alicia"},
+	}
+	for i, step := range steps {
+		if code := do(http.MethodPost, step.target, ""); code != http.StatusOK {
+			t.Fatalf("step %d %s: %d", i, step.target, code)
+		}
+		if got := name(); got != step.want {
+			t.Fatalf("step %d %s: name = %v, want %v", i, step.target, got, step.want)
+		}
+	}
+
+	// The row changes behind the API's back, so redoing the delete must be refused.
+	if _, err := api.db.Exec(ctx, "UPDATE users SET name = 'bob' WHERE id = 1"); err != nil {
+		t.Fatalf("external update: %v", err)
+	}
+	if code := do(http.MethodPost, "/api/redo", ""); code != http.StatusConflict {
+		t.Fatalf("expected 409 for a changed row, got %d", code)
+	}
+	if got := name(); got != "bob" {
+		t.Fatalf("row should be untouched, got %v", got)
+	}
+	if code := do(http.MethodPost, "/api/redo", ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 with nothing to redo, got %d", code)
+	}
+
+	// An undo that fails for another reason keeps its entry for another try.
+	if code := do(http.MethodPost, "/api/tables/users/rows", `{"id":2,"name":"carol"}`); code != http.StatusCreated {
+		t.Fatalf("insert: %d", code)
+	}
+	if _, err := api.db.Exec(ctx, "ALTER TABLE users RENAME TO users_away"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if code := do(http.MethodPost, "/api/undo", ""); code == http.StatusOK || code == http.StatusConflict {
+		t.Fatalf("expected the undo to fail, got %d", code)
+	}
+	if _, err := api.db.Exec(ctx, "ALTER TABLE users_away RENAME TO users"); err != nil {
+		t.Fatalf("rename back: %v", err)
+	}
+	if code := do(http.MethodPost, "/api/undo", ""); code != http.StatusOK {
+		t.Fatalf("expected the undo to be retried, got %d", code)
+	}
+	if n, _ := api.db.Count(ctx, "users"); n != 1 {
+		t.Fatalf("expected the inserted row to be removed, %d rows left", n)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var ErrNotConnected = errors.New("database not connected")
@@ -16,11 +18,16 @@ type (
 )
 
 type ForeignKey struct {
-	RefTable string
-	FromCol  string
-	ToCol    string
-	OnDelete ForeignKeyAction
-	OnUpdate ForeignKeyAction
+	// Constraint identifies the FOREIGN KEY constraint within its table; the parts of a
+	// composite foreign key share the same value.
+	Constraint string
+	// RefSchema is set when the referenced table lives in another schema than the referencing one.
+	RefSchema string
+	RefTable  string
+	FromCol   string
+	ToCol     string
+	OnDelete  ForeignKeyAction
+	OnUpdate  ForeignKeyAction
 }
 
 const (
@@ -31,6 +38,22 @@ const (
 	ForeignKeyActionCascade    ForeignKeyAction = "CASCADE"
 )
 
+// ErrForeignKeyAction is returned for an ON UPDATE or ON DELETE action that is not one of
+// the ForeignKeyAction constants.
+var ErrForeignKeyAction = errors.New("unknown foreign key action")
+
+// Check returns ErrForeignKeyAction unless a is one of the ForeignKeyAction constants or
+// empty, which leaves the database default. Actions are spliced into DDL, so anything else
+// must be rejected.
+func (a ForeignKeyAction) Check() error {
+	switch a {
+	case "", ForeignKeyActionNoAction, ForeignKeyActionSetNull, ForeignKeyActionSetDefault,
+		ForeignKeyActionRestrict, ForeignKeyActionCascade:
+		return nil
+	}
+	return fmt.Errorf("%w %q", ErrForeignKeyAction, string(a))
+}
+
 type Column struct {
 	Name    string
 	Type    string
@@ -44,11 +67,49 @@ type Column struct {
 }
 
 type ColumnDef struct {
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	NotNull    bool   `json:"notNull"`
-	Default    *string
-	PrimaryKey bool
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	NotNull     bool   `json:"notNull"`
+	Default     *string
+	PrimaryKey  bool
+	ForeignKeys []ForeignKey `json:"foreignKeys"`
+}
+
+// Definition converts an introspected column back into a ColumnDef.
+func (c Column) Definition() ColumnDef {
+	def := ColumnDef{
+		Name:        c.Name,
+		Type:        c.Type,
+		NotNull:     c.NotNull,
+		PrimaryKey:  c.PrimaryKey,
+		ForeignKeys: c.ForeignKeys,
+	}
+	if c.Default.Valid {
+		value := c.Default.String
+		def.Default = &value
+	}
+	return def
+}
+
+// DependentObject is a view or trigger that references a table or column.
+type DependentObject struct {
+	Type string `json:"type"` // "view" or "trigger"
+	Name string `json:"name"`
+}
+
+// IndexDef describes an index on one table.
+type IndexDef struct {
+	Name    string   `json:"name,omitempty"` // Derived from the table and columns when empty.
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// IndexName returns def.Name, or "<table>_<col>_..._idx" when it is empty.
+func (def IndexDef) IndexName(table string) string {
+	if def.Name != "" {
+		return def.Name
+	}
+	return table + "_" + strings.Join(def.Columns, "_") + "_idx"
 }
 
 type Database interface {
@@ -61,6 +122,9 @@ type Database interface {
 	// Ping verifies the connection is still alive.
 	Ping(ctx context.Context) error
 
+	// Schemas lists the schemas that can be passed to WithSchema.
+	Schemas(ctx context.Context) ([]string, error)
+
 	// GetTables retrieves all table names from the database.
 	Tables(ctx context.Context) ([]string, error)
 
@@ -76,18 +140,49 @@ type Database interface {
 	// DropColumn removes a existing column.
 	DropColumn(ctx context.Context, table, column string) error
 
+	// AlterColumn replaces the definition of an existing column (type, nullability, default, primary key, foreign keys).
+	// A non-empty def.Name different from column renames the column as well.
+	AlterColumn(ctx context.Context, table, column string, def ColumnDef) error
+
+	// AlterTable reshapes a table to match the given columns. Columns are matched by name,
+	// existing columns missing from the list are dropped and unknown ones are added.
+	AlterTable(ctx context.Context, table string, columns []ColumnDef) error
+
+	// RenameTable renames an existing table.
+	RenameTable(ctx context.Context, table, newName string) error
+
+	// RenameColumn renames an existing column.
+	RenameColumn(ctx context.Context, table, column, newName string) error
+
+	// Dependents lists the views and triggers that reference table, or only those referencing
+	// column when it is not empty.
+	Dependents(ctx context.Context, table, column string) ([]DependentObject, error)
+
 	// DropTable removes an existing table.
 	DropTable(ctx context.Context, table string, ifExists bool) error
 
+	// CreateIndex adds an index to table.
+	CreateIndex(ctx context.Context, table string, index IndexDef) error
+
 	// InsertRow inserts a new row into the specified table with the provided data.
 	Insert(ctx context.Context, table string, data Row) error
 
+	// InsertReturning inserts a row like Insert and returns it as stored, including generated
+	// keys and defaults.
+	InsertReturning(ctx context.Context, table string, data Row) (Row, error)
+
 	// GetRows retrieves rows from the specified table with optional limit and offset for pagination.
 	Rows(ctx context.Context, table string, limit, offset int) ([]Row, error)
 
 	// Count returns the total number of rows in the specified table.
 	Count(ctx context.Context, table string) (int64, error)
 
+	// Find retrieves the rows whose columns equal every value in match, with optional pagination.
+	Find(ctx context.Context, table string, match Key, limit, offset int) ([]Row, error)
+
+	// CountMatching returns the number of rows whose columns equal every value in match.
+	CountMatching(ctx context.Context, table string, match Key) (int64, error)
+
 	// RowsColumns retrieves rows for selected columns only, with optional pagination.
 	RowsColumns(ctx context.Context, table string, columns []string, limit, offset int) ([]Row, error)
 
@@ -101,5 +196,18 @@ type Database interface {
 
 	// ExecuteQuery executes a raw SQL query and returns the results.
 	Exec(ctx context.Context, query string, args ...any) (sql.Result, error)
+	// Placeholder returns the driver's bind parameter marker for the nth (1-based) argument.
+	Placeholder(n int) string
+	// Query runs a statement and returns its rows, at most as many as the RowLimit in ctx allows.
 	Query(ctx context.Context, query string, args ...any) ([]Row, error)
+	// Explain returns the plan the database would use for query.
+	Explain(ctx context.Context, query string, opts ExplainOptions, args ...any) (*QueryPlan, error)
+	// SuggestIndexes proposes indexes that would avoid the full table scans and sorts in the
+	// plan of query.
+	SuggestIndexes(ctx context.Context, query string, args ...any) ([]IndexSuggestion, error)
+
+	// WatchChanges blocks until ctx is done, calling changed with the schema ("" when the
+	// driver has none) and name of each table modified by another client. Changes made
+	// through this connection are not reported.
+	WatchChanges(ctx context.Context, changed func(schema, table string)) error
 }
@@ -0,0 +1,61 @@
+package database
+
+// ExplainOptions select the extra information Explain collects. Analyze runs the statement
+// to measure actual rows and times; drivers roll back whatever it changed.
+type ExplainOptions struct {
+	Analyze bool `json:"analyze"`
+	Buffers bool `json:"buffers"`
+}
+
+// QueryPlan is the plan of one statement as a tree of driver-independent nodes.
+type QueryPlan struct {
+	Nodes       []*PlanNode `json:"nodes"`
+	PlanningMs  *float64    `json:"planningMs,omitempty"`
+	ExecutionMs *float64    `json:"executionMs,omitempty"`
+	Warnings    []string    `json:"warnings,omitempty"`
+	Raw         any         `json:"raw"` // The driver's own output.
+}
+
+// PlanNode is one step of a plan. Type is the driver's name for it ("SCAN", "Seq Scan",
+// "Sort", ...); FullScan and Sort flag the steps that read a whole table or sort without an
+// index. Row counts and costs are nil when the driver does not report them.
+type PlanNode struct {
+	Type          string           `json:"type"`
+	Relation      string           `json:"relation,omitempty"`
+	Schema        string           `json:"schema,omitempty"` // Set when Relation, then qualified, is in another schema than the one addressed.
+	Index         string           `json:"index,omitempty"`
+	Detail        string           `json:"detail,omitempty"`
+	FullScan      bool             `json:"fullScan"`
+	Sort          bool             `json:"sort"`
+	Filter        string           `json:"filter,omitempty"`
+	SortKey       []string         `json:"sortKey,omitempty"`
+	EstimatedRows *float64         `json:"estimatedRows,omitempty"`
+	ActualRows    *float64         `json:"actualRows,omitempty"`
+	Cost          *float64         `json:"cost,omitempty"`
+	ActualTimeMs  *float64         `json:"actualTimeMs,omitempty"`
+	Buffers       map[string]int64 `json:"buffers,omitempty"`
+	Children      []*PlanNode      `json:"children,omitempty"`
+}
+
+// Walk calls fn for every node of the plan, parents before children.
+func (p *QueryPlan) Walk(fn func(*PlanNode)) {
+	var walk func([]*PlanNode)
+	walk = func(nodes []*PlanNode) {
+		for _, n := range nodes {
+			fn(n)
+			walk(n.Children)
+		}
+	}
+	walk(p.Nodes)
+}
+
+// IndexSuggestion is an index proposed for a query. Verified is set when the planner was
+// seen using the index; otherwise the suggestion comes from heuristics on the plan.
+type IndexSuggestion struct {
+	Schema    string   `json:"schema,omitempty"` // Set when Table is in another schema than the one addressed.
+	Table     string   `json:"table"`
+	Index     IndexDef `json:"index"`
+	Statement string   `json:"statement"`
+	Reason    string   `json:"reason"`
+	Verified  bool     `json:"verified"`
+}
@@ -0,0 +1,33 @@
+package database
+
+import "context"
+
+// RowLimit caps the rows a Query call loads. Drivers stop reading once Max rows are
+// collected and set Truncated when the statement had more.
+type RowLimit struct {
+	Max       int
+	Truncated bool
+}
+
+type rowLimitKey struct{}
+
+// WithRowLimit returns a context whose Query calls load at most limit.Max rows.
+func WithRowLimit(ctx context.Context, limit *RowLimit) context.Context {
+	return context.WithValue(ctx, rowLimitKey{}, limit)
+}
+
+// RowLimitFromContext returns the row limit carried by ctx, or nil when rows are unlimited.
+func RowLimitFromContext(ctx context.Context) *RowLimit {
+	limit, _ := ctx.Value(rowLimitKey{}).(*RowLimit)
+	return limit
+}
+
+// Full reports whether n collected rows use up the limit, marking the result truncated
+// since the caller only asks when another row is available. A nil limit is never full.
+func (l *RowLimit) Full(n int) bool {
+	if l == nil || l.Max <= 0 || n < l.Max {
+		return false
+	}
+	l.Truncated = true
+	return true
+}
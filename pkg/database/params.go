@@ -0,0 +1,164 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrMissingParam = errors.New("missing value for parameter")
+
+// NamedParams returns the names of the :name and @name placeholders in query, in order of
+// first use.
+func NamedParams(query string) []string {
+	var names []string
+	seen := map[string]bool{}
+	scanNamedParams(query, func(name string, _, _ int) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// BindNamed replaces the :name and @name placeholders in query with the driver's positional
+// ones, made by placeholder for the 1st, 2nd, ... argument, and returns the arguments in the
+// same order, so one statement runs on every driver. Values are never spliced into the
+// statement; placeholders inside string literals, quoted identifiers and comments are left
+// alone.
+func BindNamed(query string, params map[string]any, placeholder func(n int) string) (string, []any, error) {
+	var (
+		b    strings.Builder
+		args []any
+		err  error
+		last int
+	)
+	scanNamedParams(query, func(name string, start, end int) {
+		value, ok := params[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("%w :%s", ErrMissingParam, name)
+			}
+			return
+		}
+		args = append(args, value)
+		b.WriteString(query[last:start])
+		b.WriteString(placeholder(len(args)))
+		last = end
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	b.WriteString(query[last:])
+	return b.String(), args, nil
+}
+
+// scanNamedParams calls found with the name and byte range of each :name or @name
+// placeholder outside literals and comments. PostgreSQL casts (::type) and operators such as
+// @@ are not placeholders, even without a space after them.
+func scanNamedParams(query string, found func(name string, start, end int)) {
+	for i := 0; i < len(query); i++ {
+		if end := skipLiteral(query, i); end >= 0 {
+			i = end
+			continue
+		}
+		switch c := query[i]; {
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			i++
+		case (c == ':' || c == '@') && i+1 < len(query) && isParamStart(query[i+1]) && (i == 0 || !isParamChar(query[i-1]) && query[i-1] != c):
+			end := i + 2
+			for end < len(query) && isParamChar(query[end]) {
+				end++
+			}
+			found(query[i+1:end], i, end)
+			i = end - 1
+		}
+	}
+}
+
+// skipLiteral returns the index of the last byte of the string literal (including
+// PostgreSQL escape strings like E'...' and dollar-quoted ones), quoted identifier or
+// comment starting at query[i], or -1 when none starts there.
+func skipLiteral(query string, i int) int {
+	switch c := query[i]; {
+	case c == '\'' && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i == 1 || !isParamChar(query[i-2])):
+		return skipEscaped(query, i)
+	case c == '\'' || c == '"' || c == '`':
+		return skipQuoted(query, i, c)
+	case c == '$' && (i == 0 || !isParamChar(query[i-1])):
+		tag := dollarTag(query[i:])
+		if tag == "" {
+			return -1
+		}
+		if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+			return i + len(tag) + end + len(tag) - 1
+		}
+		return len(query) - 1
+	case c == '-' && strings.HasPrefix(query[i:], "--"):
+		if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return i + end - 1
+		}
+		return len(query) - 1
+	case c == '/' && strings.HasPrefix(query[i:], "/*"):
+		if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+			return i + end + 3
+		}
+		return len(query) - 1
+	}
+	return -1
+}
+
+// skipQuoted returns the index of the quote closing the literal or identifier opened at
+// start, or the last index when it is not closed. Doubled quotes are escapes.
+func skipQuoted(query string, start int, quote byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(query) - 1
+}
+
+// skipEscaped is skipQuoted for PostgreSQL escape strings (E'...'), where a backslash
+// escapes the next character.
+func skipEscaped(query string, start int) int {
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case query[i] == '\\':
+			i++
+		case query[i] == '\'' && i+1 < len(query) && query[i+1] == '\'':
+			i++
+		case query[i] == '\'':
+			return i
+		}
+	}
+	return len(query) - 1
+}
+
+// dollarTag returns the opening $tag$ or $$ of a PostgreSQL dollar-quoted string at the
+// start of s, or "" when s does not start one ($1 is a positional parameter).
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case !isParamStart(s[i]) && !(i > 1 && isParamChar(s[i])):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isParamStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isParamChar(c byte) bool {
+	return isParamStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	query := `SELECT id::text, ':skip' AS "a:b" -- :comment
+FROM users /* :block */ WHERE id = :id OR parent = :id AND name = :name`
+	dollar := func(n int) string { return fmt.Sprintf("$%d", n) }
+
+	got, args, err := BindNamed(query, map[string]any{"id": 7, "name": "o'hara", "unused": 1}, dollar)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := `SELECT id::text, ':skip' AS "a:b" -- :comment
+FROM users /* :block */ WHERE id = $1 OR parent = $2 AND name = $3`
+	if got != want || !reflect.DeepEqual(args, []any{7, 7, "o'hara"}) {
+		t.Fatalf("unexpected binding:\n%s\n%v", got, args)
+	}
+	if names := NamedParams(query); !reflect.DeepEqual(names, []string{"id", "name"}) {
+		t.Fatalf("unexpected names %v", names)
+	}
+	if _, _, err := BindNamed(query, map[string]any{"id": 1}, dollar); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("expected a missing parameter error, got %v", err)
+	}
+}
+
+func TestBindNamedSkipsLiterals(t *testing.T) {
+	query := "SELECT E'it\\'s :no', $$ :no $$, $fn$ @no $fn$, `c:no`, a$b, email@example, @id, :id2 FROM t WHERE x = $1"
+	got, args, err := BindNamed(query, map[string]any{"id": 1, "id2": 2}, func(int) string { return "?" })
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := "SELECT E'it\\'s :no', $$ :no $$, $fn$ @no $fn$, `c:no`, a$b, email@example, ?, ? FROM t WHERE x = $1"
+	if got != want || !reflect.DeepEqual(args, []any{1, 2}) {
+		t.Fatalf("unexpected binding:\n%s\n%v", got, args)
+	}
+}
+
+func TestBindNamedSkipsDoubledOperators(t *testing.T) {
+	query := "SELECT * FROM docs WHERE body @@to_tsquery(:q) AND tags @@ @tag"
+	got, args, err := BindNamed(query, map[string]any{"q": "cat", "tag": "pets"}, func(n int) string { return fmt.Sprintf("$%d", n) })
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := "SELECT * FROM docs WHERE body @@to_tsquery($1) AND tags @@ $2"
+	if got != want || !reflect.DeepEqual(args, []any{"cat", "pets"}) {
+		t.Fatalf("unexpected binding:\n%s\n%v", got, args)
+	}
+}
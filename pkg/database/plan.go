@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// Plan collects the statements a schema change would run without executing them.
+// Drivers record into the plan found in the context passed to their DDL methods.
+type Plan struct {
+	Statements []string `json:"statements"`
+	Warnings   []string `json:"warnings"`
+}
+
+type planKey struct{}
+
+// WithPlan returns a context that turns DDL calls into dry runs recorded into plan.
+func WithPlan(ctx context.Context, plan *Plan) context.Context {
+	return context.WithValue(ctx, planKey{}, plan)
+}
+
+// PlanFromContext returns the dry-run plan carried by ctx, or nil when statements should be executed.
+func PlanFromContext(ctx context.Context) *Plan {
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	return plan
+}
+
+func (p *Plan) Add(stmts ...string) {
+	p.Statements = append(p.Statements, stmts...)
+}
+
+func (p *Plan) Warn(format string, args ...any) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,139 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backend is one server process from pg_stat_activity. DurationMs is how long the current
+// query (or, when idle, the last one) has been running. BlockedBy lists the pids holding
+// locks this backend waits for.
+type Backend struct {
+	PID           int        `json:"pid"`
+	User          string     `json:"user,omitempty"`
+	Database      string     `json:"database,omitempty"`
+	Application   string     `json:"application,omitempty"`
+	ClientAddr    string     `json:"clientAddr,omitempty"`
+	State         string     `json:"state,omitempty"`
+	WaitEventType string     `json:"waitEventType,omitempty"`
+	WaitEvent     string     `json:"waitEvent,omitempty"`
+	Query         string     `json:"query,omitempty"`
+	QueryStart    *time.Time `json:"queryStart,omitempty"`
+	DurationMs    int64      `json:"durationMs"`
+	BlockedBy     []int      `json:"blockedBy,omitempty"`
+	Self          bool       `json:"self"`
+}
+
+// Lock is one row of pg_locks, with the relation name resolved when the lock is on one.
+type Lock struct {
+	PID      int    `json:"pid"`
+	Type     string `json:"type"`
+	Relation string `json:"relation,omitempty"`
+	Mode     string `json:"mode"`
+	Granted  bool   `json:"granted"`
+}
+
+// Activity lists the client backends of the server, longest-running queries first.
+func (p *Postgres) Activity(ctx context.Context) ([]Backend, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT a.pid, a.usename, a.datname, a.application_name, host(a.client_addr), a.state,
+			a.wait_event_type, a.wait_event, a.query, a.query_start,
+			(EXTRACT(EPOCH FROM (clock_timestamp() - a.query_start)) * 1000)::bigint,
+			pg_catalog.array_to_string(pg_catalog.pg_blocking_pids(a.pid), ','),
+			a.pid = pg_catalog.pg_backend_pid()
+		FROM pg_catalog.pg_stat_activity a
+		WHERE a.backend_type = 'client backend'
+		ORDER BY a.query_start NULLS LAST, a.pid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backends []Backend
+	for rows.Next() {
+		var (
+			b                                                     Backend
+			user, dbname, app, addr, state, waitType, wait, query sql.NullString
+			start                                                 sql.NullTime
+			millis                                                sql.NullInt64
+			blockedBy                                             string
+		)
+		if err := rows.Scan(&b.PID, &user, &dbname, &app, &addr, &state, &waitType, &wait, &query, &start, &millis, &blockedBy, &b.Self); err != nil {
+			return nil, err
+		}
+		b.User, b.Database, b.Application, b.ClientAddr = user.String, dbname.String, app.String, addr.String
+		b.State, b.WaitEventType, b.WaitEvent, b.Query = state.String, waitType.String, wait.String, query.String
+		if start.Valid {
+			b.QueryStart = &start.Time
+		}
+		b.DurationMs = millis.Int64
+		b.BlockedBy = parsePIDs(blockedBy)
+		backends = append(backends, b)
+	}
+	return backends, rows.Err()
+}
+
+// Locks lists the locks held or awaited by backends, ungranted ones first.
+func (p *Postgres) Locks(ctx context.Context) ([]Lock, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT l.pid, l.locktype,
+			CASE WHEN c.oid IS NOT NULL THEN n.nspname || '.' || c.relname END,
+			l.mode, l.granted
+		FROM pg_catalog.pg_locks l
+		LEFT JOIN pg_catalog.pg_class c ON c.oid = l.relation
+		LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE l.pid IS NOT NULL AND l.pid <> pg_catalog.pg_backend_pid()
+		ORDER BY l.granted, l.pid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []Lock
+	for rows.Next() {
+		var (
+			l        Lock
+			relation sql.NullString
+		)
+		if err := rows.Scan(&l.PID, &l.Type, &relation, &l.Mode, &l.Granted); err != nil {
+			return nil, err
+		}
+		l.Relation = relation.String
+		locks = append(locks, l)
+	}
+	return locks, rows.Err()
+}
+
+// CancelBackend cancels the current query of the backend pid, or ends its session when
+// terminate is set. It reports false when no such backend exists.
+func (p *Postgres) CancelBackend(ctx context.Context, pid int, terminate bool) (bool, error) {
+	if err := p.ensureConnected(); err != nil {
+		return false, err
+	}
+	query := "SELECT pg_catalog.pg_cancel_backend($1)"
+	if terminate {
+		query = "SELECT pg_catalog.pg_terminate_backend($1)"
+	}
+	var ok bool
+	err := p.db.QueryRowContext(ctx, query, pid).Scan(&ok)
+	return ok, err
+}
+
+func parsePIDs(list string) []int {
+	var pids []int
+	for _, field := range strings.Split(list, ",") {
+		if pid, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
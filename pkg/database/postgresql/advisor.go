@@ -0,0 +1,179 @@
+package postgresql
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+)
+
+// filterColumn matches a column compared in a plan condition, e.g. "(age > 30)",
+// "((email)::text = 'x'::text)" or "(u.status IS NULL)".
+var filterColumn = regexp.MustCompile(`([A-Za-z_][\w$]*|"(?:[^"]|"")+")\)?(?:::[\w ]+?)?\s*(=|<>|<=|>=|<|>|~~|IS\b)`)
+
+// indexTarget collects what the plan says about one table.
+type indexTarget struct {
+	schema, table        string // schema is "" for the schema carried by the context.
+	equal, ranged, order []string
+	reasons              []string
+}
+
+// SuggestIndexes proposes indexes from heuristics on the plan: a Seq Scan with a filter gets
+// an index on the filtered columns (equality comparisons first), and a Sort over a single
+// table gets those columns followed by the sort keys. The suggestions are not verified.
+func (p *Postgres) SuggestIndexes(ctx context.Context, query string, args ...any) ([]database.IndexSuggestion, error) {
+	plan, err := p.Explain(ctx, query, database.ExplainOptions{}, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]*indexTarget)
+	var order []string
+	// Targets are keyed by the relation as the plan shows it, qualified when it lives in
+	// another schema.
+	target := func(n *database.PlanNode) *indexTarget {
+		t, ok := targets[n.Relation]
+		if !ok {
+			t = &indexTarget{schema: n.Schema, table: strings.TrimPrefix(n.Relation, n.Schema+".")}
+			if n.Schema == "" {
+				t.table = n.Relation
+			}
+			targets[n.Relation] = t
+			order = append(order, n.Relation)
+		}
+		return t
+	}
+	columns := make(map[string]map[string]string)
+	columnsOf := func(n *database.PlanNode) map[string]string {
+		if cols, ok := columns[n.Relation]; ok {
+			return cols
+		}
+		t := target(n)
+		cols := make(map[string]string)
+		if list, err := p.Columns(t.context(ctx), t.table); err == nil {
+			for _, col := range list {
+				cols[strings.ToLower(col.Name)] = col.Name
+			}
+		}
+		columns[n.Relation] = cols
+		return cols
+	}
+
+	plan.Walk(func(n *database.PlanNode) {
+		switch {
+		case n.FullScan && n.Relation != "" && n.Filter != "":
+			cols := columnsOf(n)
+			t := target(n)
+			for _, m := range filterColumn.FindAllStringSubmatch(n.Filter, -1) {
+				col, ok := cols[strings.ToLower(unquoteIdent(m[1]))]
+				if !ok {
+					continue
+				}
+				if m[2] == "=" || m[2] == "IS" {
+					t.equal = appendUnique(t.equal, col)
+				} else {
+					t.ranged = appendUnique(t.ranged, col)
+				}
+			}
+			t.reasons = append(t.reasons, "Seq Scan on "+n.Relation+" with Filter: "+n.Filter)
+		case n.Sort && len(n.SortKey) > 0:
+			scan := firstRelation(n)
+			if scan == nil {
+				return
+			}
+			cols := columnsOf(scan)
+			var keys []string
+			for _, key := range n.SortKey {
+				col, ok := sortKeyColumn(key, cols)
+				if !ok {
+					return // Sorting by an expression or another table's column.
+				}
+				keys = append(keys, col)
+			}
+			t := target(scan)
+			t.order = keys
+			t.reasons = append(t.reasons, "Sort by "+strings.Join(n.SortKey, ", "))
+		}
+	})
+
+	suggestions := []database.IndexSuggestion{}
+	for _, relation := range order {
+		t := targets[relation]
+		cols := append([]string{}, t.equal...)
+		if len(t.order) > 0 {
+			for _, col := range t.order {
+				cols = appendUnique(cols, col)
+			}
+		} else if len(t.ranged) > 0 {
+			cols = appendUnique(cols, t.ranged[0])
+		}
+		if len(cols) == 0 {
+			continue
+		}
+		def := database.IndexDef{Columns: cols}
+		stmt, err := buildCreateIndex(t.context(ctx), t.table, def)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, database.IndexSuggestion{
+			Schema:    t.schema,
+			Table:     t.table,
+			Index:     def,
+			Statement: stmt,
+			Reason:    strings.Join(t.reasons, "; "),
+		})
+	}
+	return suggestions, nil
+}
+
+// context returns ctx addressing the schema of the target's table.
+func (t *indexTarget) context(ctx context.Context) context.Context {
+	if t.schema == "" {
+		return ctx
+	}
+	return database.WithSchema(ctx, t.schema)
+}
+
+// firstRelation returns the first node below n that reads a table.
+func firstRelation(n *database.PlanNode) *database.PlanNode {
+	for _, child := range n.Children {
+		if child.Relation != "" {
+			return child
+		}
+		if found := firstRelation(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// sortKeyColumn maps a sort key such as "u.name DESC" to a column of cols.
+func sortKeyColumn(key string, cols map[string]string) (string, bool) {
+	fields := strings.Fields(key)
+	if len(fields) == 0 {
+		return "", false
+	}
+	name := fields[0]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	col, ok := cols[strings.ToLower(unquoteIdent(name))]
+	return col, ok
+}
+
+func unquoteIdent(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return name
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,385 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"sqlite-gui/pkg/database"
+)
+
+func (p *Postgres) AlterColumn(ctx context.Context, table, column string, def database.ColumnDef) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" || strings.TrimSpace(column) == "" {
+		return fmt.Errorf("table and column are required")
+	}
+	if def.Name == "" {
+		def.Name = column
+	}
+	current, err := p.Columns(ctx, table)
+	if err != nil {
+		return err
+	}
+	columns := make([]database.ColumnDef, 0, len(current))
+	found := false
+	for _, col := range current {
+		if col.Name == column {
+			columns = append(columns, def)
+			found = true
+			continue
+		}
+		columns = append(columns, col.Definition())
+	}
+	if !found {
+		return fmt.Errorf("column %s not found in %s", column, table)
+	}
+	var renames map[string]string
+	if def.Name != column {
+		renames = map[string]string{column: def.Name}
+	}
+	return p.alterTable(ctx, table, columns, renames)
+}
+
+func (p *Postgres) AlterTable(ctx context.Context, table string, columns []database.ColumnDef) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" {
+		return fmt.Errorf("table name is required")
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+	return p.alterTable(ctx, table, columns, nil)
+}
+
+// alterTable diffs the current table against columns and applies the difference with
+// native ALTER TABLE statements inside a single transaction. renames maps old column
+// names to new ones and is applied first.
+func (p *Postgres) alterTable(ctx context.Context, table string, columns []database.ColumnDef, renames map[string]string) (err error) {
+	existing, err := p.Columns(ctx, table)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return fmt.Errorf("table %s not found", table)
+	}
+	current := make(map[string]database.Column, len(existing))
+	var currentPK []string
+	for _, col := range existing {
+		if renamed, ok := renames[col.Name]; ok {
+			col.Name = renamed
+		}
+		current[col.Name] = col
+		if col.PrimaryKey {
+			currentPK = append(currentPK, col.Name)
+		}
+	}
+	original := make(map[string]string, len(renames))
+	for from, to := range renames {
+		original[to] = from
+	}
+
+	qTable := quoteTable(ctx, table)
+	var stmts []string
+	for from, to := range renames {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", qTable, quoteIdent(from), quoteIdent(to)))
+	}
+
+	wanted := make(map[string]bool, len(columns))
+	var wantedPK []string
+	for _, col := range columns {
+		wanted[col.Name] = true
+		if col.PrimaryKey {
+			wantedPK = append(wantedPK, col.Name)
+		}
+	}
+	for _, col := range existing {
+		name := col.Name
+		if renamed, ok := renames[name]; ok {
+			name = renamed
+		}
+		if !wanted[name] {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", qTable, quoteIdent(name)))
+		}
+	}
+
+	pkChanged := !sameColumns(currentPK, wantedPK)
+	if pkChanged && len(currentPK) > 0 {
+		names, err := constraintNames(ctx, p.db, table, "p", "")
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", qTable, quoteIdent(name)))
+		}
+	}
+
+	for _, col := range columns {
+		cur, ok := current[col.Name]
+		if !ok {
+			add := col
+			add.PrimaryKey = false
+			definition, err := buildColumnDefinition(schemaOf(ctx), add, false)
+			if err != nil {
+				return err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", qTable, definition))
+			continue
+		}
+		// Constraint lookups happen before the renames run, so they need the original column name.
+		lookup := col.Name
+		if from, ok := original[col.Name]; ok {
+			lookup = from
+		}
+		changes, err := p.alterColumnStatements(ctx, table, lookup, cur, col)
+		if err != nil {
+			return err
+		}
+		stmts = append(stmts, changes...)
+	}
+
+	if pkChanged && len(wantedPK) > 0 {
+		quoted := make([]string, len(wantedPK))
+		for i, c := range wantedPK {
+			quoted[i] = quoteIdent(c)
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s)", qTable, strings.Join(quoted, ", ")))
+	}
+
+	if plan := database.PlanFromContext(ctx); plan != nil {
+		plan.Add("BEGIN")
+		plan.Add(stmts...)
+		plan.Add("COMMIT")
+		return nil
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	for _, stmt := range stmts {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w (while running %q)", err, stmt)
+		}
+	}
+	return tx.Commit()
+}
+
+// alterColumnStatements returns the ALTER COLUMN and constraint statements that turn cur into def.
+// Primary key changes are handled at table level by alterTable.
+func (p *Postgres) alterColumnStatements(ctx context.Context, table, lookup string, cur database.Column, def database.ColumnDef) ([]string, error) {
+	if strings.TrimSpace(def.Type) == "" {
+		return nil, fmt.Errorf("column name and type are required")
+	}
+	qTable := quoteTable(ctx, table)
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", qTable, quoteIdent(def.Name))
+	var stmts []string
+	same, err := p.sameType(ctx, table, lookup, cur.Type, def.Type)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		stmts = append(stmts, prefix+fmt.Sprintf("TYPE %s USING %s::%s", def.Type, quoteIdent(def.Name), def.Type))
+	}
+	switch {
+	case def.NotNull && !cur.NotNull:
+		stmts = append(stmts, prefix+"SET NOT NULL")
+	case !def.NotNull && cur.NotNull && !def.PrimaryKey:
+		stmts = append(stmts, prefix+"DROP NOT NULL")
+	}
+	switch {
+	case def.Default == nil && cur.Default.Valid:
+		stmts = append(stmts, prefix+"DROP DEFAULT")
+	case def.Default != nil && (!cur.Default.Valid || cur.Default.String != *def.Default):
+		stmts = append(stmts, prefix+"SET DEFAULT "+*def.Default)
+	}
+	if !sameForeignKeys(cur.ForeignKeys, def.ForeignKeys) {
+		names, err := constraintNames(ctx, p.db, table, "f", lookup)
+		if err != nil {
+			return nil, err
+		}
+		composite, err := compositeConstraints(ctx, p.db, table, lookup)
+		if err != nil {
+			return nil, err
+		}
+		// A foreign key spanning several columns cannot be rebuilt from one of them: it is
+		// kept as it is while the column still lists it, and dropped as a whole otherwise.
+		kept := map[string]bool{}
+		for _, fk := range def.ForeignKeys {
+			if !composite[fk.Constraint] {
+				continue
+			}
+			if !sameForeignKeys(constraintParts(cur.ForeignKeys, fk.Constraint), constraintParts(def.ForeignKeys, fk.Constraint)) {
+				return nil, fmt.Errorf("foreign key %s spans several columns and cannot be changed through column %s", fk.Constraint, def.Name)
+			}
+			kept[fk.Constraint] = true
+		}
+		for _, name := range names {
+			if !kept[name] {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", qTable, quoteIdent(name)))
+			}
+		}
+		for _, fk := range def.ForeignKeys {
+			if !composite[fk.Constraint] {
+				references, err := buildReferences(schemaOf(ctx), fk)
+				if err != nil {
+					return nil, err
+				}
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD FOREIGN KEY (%s) %s", qTable, quoteIdent(def.Name), references))
+			}
+		}
+	}
+	return stmts, nil
+}
+
+// constraintNames lists the constraints of the given pg_constraint.contype on table in the schema carried by ctx.
+// When column is set only constraints covering that column are returned.
+func constraintNames(ctx context.Context, db *sql.DB, table, contype, column string) ([]string, error) {
+	query := `
+		SELECT DISTINCT con.conname
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class rel ON rel.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace nsp ON nsp.oid = rel.relnamespace
+		JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = ANY(con.conkey)
+		WHERE nsp.nspname = $4 AND rel.relname = $1 AND con.contype = $2::"char" AND ($3::text = '' OR att.attname = $3::text)
+		ORDER BY con.conname
+	`
+	rows, err := db.QueryContext(ctx, query, table, contype, column, schemaOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// compositeConstraints returns the foreign keys on column of table, in the schema carried by
+// ctx, that span more than one column.
+func compositeConstraints(ctx context.Context, db *sql.DB, table, column string) (map[string]bool, error) {
+	query := `
+		SELECT con.conname
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class rel ON rel.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace nsp ON nsp.oid = rel.relnamespace
+		JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = ANY(con.conkey)
+		WHERE nsp.nspname = $3 AND rel.relname = $1 AND con.contype = 'f' AND att.attname = $2
+			AND cardinality(con.conkey) > 1
+	`
+	rows, err := db.QueryContext(ctx, query, table, column, schemaOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
+
+// constraintParts returns the entries of fks that belong to constraint.
+func constraintParts(fks []database.ForeignKey, constraint string) []database.ForeignKey {
+	var parts []database.ForeignKey
+	for _, fk := range fks {
+		if fk.Constraint == constraint {
+			parts = append(parts, fk)
+		}
+	}
+	return parts
+}
+
+// sameType reports whether column of table already has type typ. information_schema only
+// names the base type, and aliases such as int4 and integer or varchar(255) and character
+// varying(255) would otherwise rewrite the table for nothing, so both sides are compared as
+// format_type renders them.
+func (p *Postgres) sameType(ctx context.Context, table, column, current, typ string) (bool, error) {
+	if strings.EqualFold(current, typ) {
+		return true, nil
+	}
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var (
+		oid    uint32
+		typmod int32
+	)
+	// Describing SELECT NULL::typ yields the type and modifier the server resolves typ to.
+	err = conn.Raw(func(driverConn any) error {
+		desc, err := driverConn.(*stdlib.Conn).Conn().PgConn().Prepare(ctx, "", "SELECT NULL::"+typ, nil)
+		if err != nil {
+			return err
+		}
+		if len(desc.Fields) != 1 {
+			return fmt.Errorf("invalid type %s", typ)
+		}
+		oid, typmod = desc.Fields[0].DataTypeOID, desc.Fields[0].TypeModifier
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	var wanted, have string
+	err = conn.QueryRowContext(ctx, `
+		SELECT pg_catalog.format_type($1, $2), pg_catalog.format_type(att.atttypid, att.atttypmod)
+		FROM pg_catalog.pg_attribute att
+		JOIN pg_catalog.pg_class rel ON rel.oid = att.attrelid
+		JOIN pg_catalog.pg_namespace nsp ON nsp.oid = rel.relnamespace
+		WHERE nsp.nspname = $5 AND rel.relname = $3 AND att.attname = $4`,
+		oid, typmod, table, column, schemaOf(ctx)).Scan(&wanted, &have)
+	if err != nil {
+		return false, err
+	}
+	return wanted == have, nil
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameForeignKeys(a, b []database.ForeignKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].RefSchema != b[i].RefSchema || a[i].RefTable != b[i].RefTable || a[i].ToCol != b[i].ToCol ||
+			a[i].OnDelete != b[i].OnDelete || a[i].OnUpdate != b[i].OnUpdate {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// DatabaseInfo describes one database on the server. Size is nil when the current user may
+// not connect to the database.
+type DatabaseInfo struct {
+	Name      string `json:"name"`
+	Owner     string `json:"owner"`
+	Encoding  string `json:"encoding"`
+	Size      *int64 `json:"size,omitempty"` // Bytes on disk.
+	AllowConn bool   `json:"allowConnections"`
+	Current   bool   `json:"current"`
+}
+
+// Databases lists the non-template databases of the server the connection points at.
+func (p *Postgres) Databases(ctx context.Context) ([]DatabaseInfo, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT d.datname,
+			pg_catalog.pg_get_userbyid(d.datdba),
+			pg_catalog.pg_encoding_to_char(d.encoding),
+			CASE WHEN pg_catalog.has_database_privilege(d.datname, 'CONNECT')
+				THEN pg_catalog.pg_database_size(d.datname) END,
+			d.datallowconn,
+			d.datname = pg_catalog.current_database()
+		FROM pg_catalog.pg_database d
+		WHERE NOT d.datistemplate
+		ORDER BY d.datname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var databases []DatabaseInfo
+	for rows.Next() {
+		var (
+			info DatabaseInfo
+			size sql.NullInt64
+		)
+		if err := rows.Scan(&info.Name, &info.Owner, &info.Encoding, &size, &info.AllowConn, &info.Current); err != nil {
+			return nil, err
+		}
+		if size.Valid {
+			info.Size = &size.Int64
+		}
+		databases = append(databases, info)
+	}
+	return databases, rows.Err()
+}
+
+// WithDatabase returns conn pointed at dbname, keeping its host, credentials and parameters.
+func WithDatabase(conn, dbname string) (string, error) {
+	if dbname == "" || strings.ContainsAny(dbname, "/?#") {
+		return "", errors.New("invalid database name " + dbname)
+	}
+	u, err := url.Parse(conn)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "postgresql" && u.Scheme != "postgres" {
+		return "", errors.New("not a PostgreSQL connection URL")
+	}
+	query := u.Query()
+	if query.Has("dbname") {
+		query.Set("dbname", dbname)
+		u.RawQuery = query.Encode()
+	}
+	u.Path = "/" + dbname
+	u.RawPath = ""
+	return u.String(), nil
+}
@@ -0,0 +1,129 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+)
+
+// Explain runs EXPLAIN (FORMAT JSON, VERBOSE) on query; VERBOSE names the schema of each
+// relation. With opts.Analyze the statement is executed inside a transaction that is always
+// rolled back.
+func (p *Postgres) Explain(ctx context.Context, query string, opts database.ExplainOptions, args ...any) (*database.QueryPlan, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	options := []string{"FORMAT JSON", "VERBOSE"}
+	if opts.Analyze {
+		options = append(options, "ANALYZE")
+	}
+	if opts.Buffers {
+		options = append(options, "BUFFERS")
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var output string
+	if err := tx.QueryRowContext(ctx, "EXPLAIN ("+strings.Join(options, ", ")+") "+query, args...).Scan(&output); err != nil {
+		return nil, err
+	}
+	return parseExplainJSON([]byte(output), schemaOf(ctx))
+}
+
+// parseExplainJSON converts EXPLAIN JSON output; relations outside schema, the one the
+// request addresses, are qualified with theirs.
+func parseExplainJSON(data []byte, schema string) (*database.QueryPlan, error) {
+	var results []struct {
+		Plan          map[string]any `json:"Plan"`
+		PlanningTime  *float64       `json:"Planning Time"`
+		ExecutionTime *float64       `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("parse EXPLAIN output: %w", err)
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	plan := &database.QueryPlan{Nodes: []*database.PlanNode{}, Raw: raw}
+	for _, result := range results {
+		plan.Nodes = append(plan.Nodes, convertPlanNode(result.Plan, schema))
+		plan.PlanningMs = result.PlanningTime
+		plan.ExecutionMs = result.ExecutionTime
+	}
+	return plan, nil
+}
+
+func convertPlanNode(raw map[string]any, schema string) *database.PlanNode {
+	text := func(key string) string {
+		s, _ := raw[key].(string)
+		return s
+	}
+	number := func(key string) *float64 {
+		if f, ok := raw[key].(float64); ok {
+			return &f
+		}
+		return nil
+	}
+
+	node := &database.PlanNode{
+		Type:          text("Node Type"),
+		Relation:      text("Relation Name"),
+		Index:         text("Index Name"),
+		EstimatedRows: number("Plan Rows"),
+		Cost:          number("Total Cost"),
+		ActualTimeMs:  number("Actual Total Time"),
+	}
+	if other := text("Schema"); other != "" && other != schema && node.Relation != "" {
+		node.Schema = other
+		node.Relation = other + "." + node.Relation
+	}
+	node.FullScan = node.Type == "Seq Scan"
+	node.Sort = node.Type == "Sort" || node.Type == "Incremental Sort"
+
+	var details []string
+	for _, key := range []string{"Index Cond", "Recheck Cond", "Hash Cond", "Merge Cond", "Join Filter", "Filter"} {
+		if cond := text(key); cond != "" {
+			details = append(details, key+": "+cond)
+		}
+	}
+	node.Detail = strings.Join(details, "; ")
+	node.Filter = text("Filter")
+	if keys, ok := raw["Sort Key"].([]any); ok {
+		for _, key := range keys {
+			if s, ok := key.(string); ok {
+				node.SortKey = append(node.SortKey, s)
+			}
+		}
+	}
+	// Actual Rows is averaged over the node's loops; report the total.
+	if rows := number("Actual Rows"); rows != nil {
+		total := *rows
+		if loops := number("Actual Loops"); loops != nil {
+			total *= *loops
+		}
+		node.ActualRows = &total
+	}
+	for key, value := range raw {
+		if f, ok := value.(float64); ok && strings.HasSuffix(key, " Blocks") {
+			if node.Buffers == nil {
+				node.Buffers = make(map[string]int64)
+			}
+			node.Buffers[key] = int64(f)
+		}
+	}
+	if children, ok := raw["Plans"].([]any); ok {
+		for _, child := range children {
+			if m, ok := child.(map[string]any); ok {
+				node.Children = append(node.Children, convertPlanNode(m, schema))
+			}
+		}
+	}
+	return node
+}
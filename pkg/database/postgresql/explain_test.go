@@ -0,0 +1,24 @@
+package postgresql
+
+import "testing"
+
+func TestParseExplainJSON(t *testing.T) {
+	plan, err := parseExplainJSON([]byte(`[{"Plan": {"Node Type": "Sort", "Sort Key": ["name"], "Total Cost": 12.5,
+		"Plan Rows": 10, "Actual Rows": 4, "Actual Loops": 1, "Plans": [{"Node Type": "Seq Scan",
+		"Relation Name": "users", "Schema": "app", "Filter": "(age > 30)", "Plan Rows": 10,
+		"Actual Rows": 2, "Actual Loops": 2, "Shared Hit Blocks": 3}]}, "Planning Time": 0.1, "Execution Time": 0.4}]`), "public")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(plan.Nodes) != 1 || plan.ExecutionMs == nil || *plan.ExecutionMs != 0.4 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	sort := plan.Nodes[0]
+	if !sort.Sort || len(sort.SortKey) != 1 || *sort.Cost != 12.5 || len(sort.Children) != 1 {
+		t.Fatalf("unexpected sort node %+v", sort)
+	}
+	scan := sort.Children[0]
+	if !scan.FullScan || scan.Relation != "app.users" || scan.Schema != "app" || scan.Filter != "(age > 30)" || *scan.ActualRows != 4 || scan.Buffers["Shared Hit Blocks"] != 3 {
+		t.Fatalf("unexpected scan node %+v", scan)
+	}
+}
@@ -0,0 +1,118 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ChangeChannel is the NOTIFY channel that change triggers publish to.
+const ChangeChannel = "sqlite_gui_changes"
+
+const changeTriggerName = "sqlite_gui_notify_change"
+
+// Listen subscribes a dedicated connection to channels and calls handle for every
+// notification until ctx is done. The connection is discarded afterwards instead of
+// being returned to the pool with its subscriptions.
+func (p *Postgres) Listen(ctx context.Context, channels []string, handle func(channel, payload string)) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return errors.New("no channels to listen on")
+	}
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	_ = conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		for _, channel := range channels {
+			if _, err := pgConn.Exec(ctx, "LISTEN "+quoteIdent(channel)); err != nil {
+				listenErr = err
+				return driver.ErrBadConn
+			}
+		}
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					listenErr = err
+				}
+				return driver.ErrBadConn
+			}
+			handle(n.Channel, n.Payload)
+		}
+	})
+	return listenErr
+}
+
+// Notify sends payload on channel with pg_notify.
+func (p *Postgres) Notify(ctx context.Context, channel, payload string) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	_, err := p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+// WatchChanges listens on ChangeChannel, which only carries events for tables that have a
+// change trigger installed (see InstallChangeTrigger).
+func (p *Postgres) WatchChanges(ctx context.Context, changed func(schema, table string)) error {
+	return p.Listen(ctx, []string{ChangeChannel}, func(_, payload string) {
+		var event struct {
+			Schema string `json:"schema"`
+			Table  string `json:"table"`
+		}
+		if err := json.Unmarshal([]byte(payload), &event); err == nil && event.Table != "" {
+			changed(event.Schema, event.Table)
+		}
+	})
+}
+
+// InstallChangeTrigger adds a statement-level trigger to table that publishes
+// {"schema": ..., "table": ..., "action": ...} on ChangeChannel after every write.
+func (p *Postgres) InstallChangeTrigger(ctx context.Context, table string) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s.%s() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+	PERFORM pg_notify('%s', json_build_object('schema', TG_TABLE_SCHEMA, 'table', TG_TABLE_NAME, 'action', lower(TG_OP))::text);
+	RETURN NULL;
+END
+$$`, quoteIdent(schemaOf(ctx)), changeTriggerName, ChangeChannel),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", changeTriggerName, quoteTable(ctx, table)),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON %s FOR EACH STATEMENT EXECUTE FUNCTION %s.%s()",
+			changeTriggerName, quoteTable(ctx, table), quoteIdent(schemaOf(ctx)), changeTriggerName),
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveChangeTrigger drops the trigger added by InstallChangeTrigger.
+func (p *Postgres) RemoveChangeTrigger(ctx context.Context, table string) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", changeTriggerName, quoteTable(ctx, table)))
+	return err
+}
@@ -4,15 +4,20 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"sqlite-gui/pkg/database"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
+const defaultSchema = "public"
+
 // Postgres implements the database.Database interface using the pgx driver.
 type Postgres struct {
 	db *sql.DB
@@ -53,6 +58,15 @@ func ensureExecMode(conn string) string {
 	return conn + "?default_query_exec_mode=exec"
 }
 
+// ReadOnlyConn makes every session opened from conn default to read-only transactions
+// through the default_transaction_read_only runtime parameter.
+func ReadOnlyConn(conn string) string {
+	if strings.Contains(conn, "?") {
+		return conn + "&default_transaction_read_only=on"
+	}
+	return conn + "?default_transaction_read_only=on"
+}
+
 func (p *Postgres) Close() error {
 	if p.db == nil {
 		return nil
@@ -69,43 +83,107 @@ func (p *Postgres) Ping(ctx context.Context) error {
 	return p.db.PingContext(ctx)
 }
 
+// Schemas lists the user schemas, leaving out pg_catalog, pg_toast and information_schema.
+func (p *Postgres) Schemas(ctx context.Context) ([]string, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT nspname FROM pg_catalog.pg_namespace
+		WHERE nspname NOT LIKE 'pg\_%' AND nspname <> 'information_schema'
+		ORDER BY nspname
+	`
+	return p.queryStrings(ctx, query)
+}
+
+// Tables lists the tables of the schema carried by ctx (public by default).
 func (p *Postgres) Tables(ctx context.Context) ([]string, error) {
 	if err := p.ensureConnected(); err != nil {
 		return nil, err
 	}
-	// Defaults to public schema for now
-	query := "SELECT tablename FROM pg_catalog.pg_tables WHERE schemaname = 'public' ORDER BY tablename"
-	rows, err := p.db.QueryContext(ctx, query)
+	query := "SELECT tablename FROM pg_catalog.pg_tables WHERE schemaname = $1 ORDER BY tablename"
+	return p.queryStrings(ctx, query, schemaOf(ctx))
+}
+
+func (p *Postgres) queryStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// Referrer is a foreign key constraint on Table that references another table. Schema is
+// set when Table lives in another schema than the referenced table.
+type Referrer struct {
+	Schema     string
+	Table      string
+	Constraint string
+}
+
+// Referrers lists the foreign keys in every schema that reference table in the schema
+// carried by ctx.
+func (p *Postgres) Referrers(ctx context.Context, table string) ([]Referrer, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT cnsp.nspname, child.relname, con.conname
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class child ON child.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace cnsp ON cnsp.oid = child.relnamespace
+		JOIN pg_catalog.pg_class parent ON parent.oid = con.confrelid
+		JOIN pg_catalog.pg_namespace pnsp ON pnsp.oid = parent.relnamespace
+		WHERE con.contype = 'f' AND parent.relname = $1 AND pnsp.nspname = $2
+		ORDER BY cnsp.nspname, child.relname, con.conname
+	`
+	schema := schemaOf(ctx)
+	rows, err := p.db.QueryContext(ctx, query, table, schema)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tables []string
+	var referrers []Referrer
 	for rows.Next() {
-		var table string
-		if err := rows.Scan(&table); err != nil {
+		var ref Referrer
+		if err := rows.Scan(&ref.Schema, &ref.Table, &ref.Constraint); err != nil {
 			return nil, err
 		}
-		tables = append(tables, table)
+		if ref.Schema == schema {
+			ref.Schema = ""
+		}
+		referrers = append(referrers, ref)
 	}
-	return tables, rows.Err()
+	return referrers, rows.Err()
 }
 
 func (p *Postgres) Columns(ctx context.Context, table string) ([]database.Column, error) {
 	if err := p.ensureConnected(); err != nil {
 		return nil, err
 	}
+	schema := schemaOf(ctx)
 
 	// 1. Get Primary Keys
 	pks := make(map[string]int)
 	pkQuery := `
 		SELECT kcu.column_name, kcu.ordinal_position
 		FROM information_schema.key_column_usage kcu
-		JOIN information_schema.table_constraints tc ON kcu.constraint_name = tc.constraint_name
-		WHERE kcu.table_name = $1 AND kcu.table_schema = 'public' AND tc.constraint_type = 'PRIMARY KEY'
+		JOIN information_schema.table_constraints tc
+			ON kcu.constraint_name = tc.constraint_name AND kcu.constraint_schema = tc.constraint_schema
+		WHERE kcu.table_name = $1 AND kcu.table_schema = $2 AND tc.constraint_type = 'PRIMARY KEY'
 	`
-	pkRows, err := p.db.QueryContext(ctx, pkQuery, table)
+	pkRows, err := p.db.QueryContext(ctx, pkQuery, table, schema)
 	if err != nil {
 		return nil, err
 	}
@@ -118,34 +196,46 @@ func (p *Postgres) Columns(ctx context.Context, table string) ([]database.Column
 		}
 	}
 
-	// 2. Get Foreign Keys
+	// 2. Get Foreign Keys (referenced columns are matched by position so composite keys pair up)
 	fks := make(map[string][]database.ForeignKey)
 	fkQuery := `
 		SELECT
+			kcu.constraint_name,
 			kcu.column_name,
-			ccu.table_name AS foreign_table_name,
-			ccu.column_name AS foreign_column_name,
+			ref.table_schema AS foreign_table_schema,
+			ref.table_name AS foreign_table_name,
+			ref.column_name AS foreign_column_name,
 			rc.update_rule,
 			rc.delete_rule
 		FROM information_schema.key_column_usage kcu
-		JOIN information_schema.referential_constraints rc ON kcu.constraint_name = rc.constraint_name
-		JOIN information_schema.constraint_column_usage ccu ON rc.constraint_name = ccu.constraint_name
-		WHERE kcu.table_name = $1 AND kcu.table_schema = 'public'
+		JOIN information_schema.referential_constraints rc
+			ON kcu.constraint_name = rc.constraint_name AND kcu.constraint_schema = rc.constraint_schema
+		JOIN information_schema.key_column_usage ref
+			ON ref.constraint_name = rc.unique_constraint_name
+			AND ref.constraint_schema = rc.unique_constraint_schema
+			AND ref.ordinal_position = kcu.position_in_unique_constraint
+		WHERE kcu.table_name = $1 AND kcu.table_schema = $2
+		ORDER BY kcu.constraint_name, kcu.ordinal_position
 	`
-	fkRows, err := p.db.QueryContext(ctx, fkQuery, table)
+	fkRows, err := p.db.QueryContext(ctx, fkQuery, table, schema)
 	if err != nil {
 		return nil, err
 	}
 	defer fkRows.Close()
 	for fkRows.Next() {
-		var col, refTable, refCol, upRule, delRule string
-		if err := fkRows.Scan(&col, &refTable, &refCol, &upRule, &delRule); err == nil {
+		var constraint, col, refSchema, refTable, refCol, upRule, delRule string
+		if err := fkRows.Scan(&constraint, &col, &refSchema, &refTable, &refCol, &upRule, &delRule); err == nil {
+			if refSchema == schema {
+				refSchema = ""
+			}
 			fks[col] = append(fks[col], database.ForeignKey{
-				RefTable: refTable,
-				FromCol:  col,
-				ToCol:    refCol,
-				OnUpdate: database.ForeignKeyAction(upRule),
-				OnDelete: database.ForeignKeyAction(delRule),
+				Constraint: constraint,
+				RefSchema:  refSchema,
+				RefTable:   refTable,
+				FromCol:    col,
+				ToCol:      refCol,
+				OnUpdate:   database.ForeignKeyAction(upRule),
+				OnDelete:   database.ForeignKeyAction(delRule),
 			})
 		}
 	}
@@ -154,10 +244,10 @@ func (p *Postgres) Columns(ctx context.Context, table string) ([]database.Column
 	colQuery := `
 		SELECT column_name, data_type, is_nullable, column_default
 		FROM information_schema.columns
-		WHERE table_name = $1 AND table_schema = 'public'
+		WHERE table_name = $1 AND table_schema = $2
 		ORDER BY ordinal_position
 	`
-	rows, err := p.db.QueryContext(ctx, colQuery, table)
+	rows, err := p.db.QueryContext(ctx, colQuery, table, schema)
 	if err != nil {
 		return nil, err
 	}
@@ -190,12 +280,11 @@ func (p *Postgres) CreateTable(ctx context.Context, name string, columns []datab
 	if err := p.ensureConnected(); err != nil {
 		return err
 	}
-	stmt, err := buildCreateTableSQL(name, columns, ifNotExists)
+	stmt, err := buildCreateTableSQL(schemaOf(ctx), name, columns, ifNotExists)
 	if err != nil {
 		return err
 	}
-	_, err = p.db.ExecContext(ctx, stmt)
-	return err
+	return p.execDDL(ctx, stmt)
 }
 
 func (p *Postgres) AddColumn(ctx context.Context, table string, column database.ColumnDef) error {
@@ -208,13 +297,12 @@ func (p *Postgres) AddColumn(ctx context.Context, table string, column database.
 	if column.PrimaryKey {
 		return fmt.Errorf("adding primary key columns via ALTER TABLE is not supported")
 	}
-	definition, err := buildColumnDefinition(column, false)
+	definition, err := buildColumnDefinition(schemaOf(ctx), column, false)
 	if err != nil {
 		return err
 	}
-	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(table), definition)
-	_, err = p.db.ExecContext(ctx, stmt)
-	return err
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteTable(ctx, table), definition)
+	return p.execDDL(ctx, stmt)
 }
 
 func (p *Postgres) DropColumn(ctx context.Context, table, column string) error {
@@ -224,9 +312,67 @@ func (p *Postgres) DropColumn(ctx context.Context, table, column string) error {
 	if strings.TrimSpace(table) == "" || strings.TrimSpace(column) == "" {
 		return fmt.Errorf("table and column are required")
 	}
-	stmt := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(table), quoteIdent(column))
-	_, err := p.db.ExecContext(ctx, stmt)
-	return err
+	stmt := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteTable(ctx, table), quoteIdent(column))
+	return p.execDDL(ctx, stmt)
+}
+
+func (p *Postgres) RenameTable(ctx context.Context, table, newName string) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" || strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("table and new name are required")
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteTable(ctx, table), quoteIdent(newName))
+	return p.execDDL(ctx, stmt)
+}
+
+func (p *Postgres) RenameColumn(ctx context.Context, table, column, newName string) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" || strings.TrimSpace(column) == "" || strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("table, column and new name are required")
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteTable(ctx, table), quoteIdent(column), quoteIdent(newName))
+	return p.execDDL(ctx, stmt)
+}
+
+// Dependents reports views through information_schema view usage and every trigger defined on the table,
+// since trigger function bodies cannot be inspected for column references.
+func (p *Postgres) Dependents(ctx context.Context, table, column string) ([]database.DependentObject, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT DISTINCT 'view', view_name
+		FROM information_schema.view_column_usage
+		WHERE table_schema = $3 AND table_name = $1 AND ($2::text = '' OR column_name = $2::text)
+		UNION
+		SELECT DISTINCT 'view', view_name
+		FROM information_schema.view_table_usage
+		WHERE table_schema = $3 AND table_name = $1 AND $2::text = ''
+		UNION
+		SELECT DISTINCT 'trigger', trigger_name
+		FROM information_schema.triggers
+		WHERE event_object_schema = $3 AND event_object_table = $1
+		ORDER BY 1, 2
+	`
+	rows, err := p.db.QueryContext(ctx, query, table, column, schemaOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []database.DependentObject
+	for rows.Next() {
+		var dep database.DependentObject
+		if err := rows.Scan(&dep.Type, &dep.Name); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
 }
 
 func (p *Postgres) DropTable(ctx context.Context, table string, ifExists bool) error {
@@ -240,16 +386,44 @@ func (p *Postgres) DropTable(ctx context.Context, table string, ifExists bool) e
 	if ifExists {
 		stmt += "IF EXISTS "
 	}
-	stmt += quoteIdent(table)
-	_, err := p.db.ExecContext(ctx, stmt)
-	return err
+	stmt += quoteTable(ctx, table)
+	return p.execDDL(ctx, stmt)
+}
+
+func (p *Postgres) CreateIndex(ctx context.Context, table string, index database.IndexDef) error {
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+	stmt, err := buildCreateIndex(ctx, table, index)
+	if err != nil {
+		return err
+	}
+	return p.execDDL(ctx, stmt)
+}
+
+func buildCreateIndex(ctx context.Context, table string, index database.IndexDef) (string, error) {
+	if strings.TrimSpace(table) == "" {
+		return "", fmt.Errorf("table name is required")
+	}
+	if len(index.Columns) == 0 {
+		return "", fmt.Errorf("at least one index column is required")
+	}
+	cols := make([]string, len(index.Columns))
+	for i, col := range index.Columns {
+		cols[i] = quoteIdent(col)
+	}
+	stmt := "CREATE INDEX "
+	if index.Unique {
+		stmt = "CREATE UNIQUE INDEX "
+	}
+	return stmt + quoteIdent(index.IndexName(table)) + " ON " + quoteTable(ctx, table) + " (" + strings.Join(cols, ", ") + ")", nil
 }
 
 func (p *Postgres) Rows(ctx context.Context, table string, limit, offset int) ([]database.Row, error) {
 	if err := p.ensureConnected(); err != nil {
 		return nil, err
 	}
-	query := fmt.Sprintf("SELECT * FROM %s", quoteIdent(table))
+	query := fmt.Sprintf("SELECT * FROM %s", quoteTable(ctx, table))
 	args := []any{}
 
 	// Postgres LIMIT/OFFSET
@@ -268,7 +442,7 @@ func (p *Postgres) Count(ctx context.Context, table string) (int64, error) {
 	if err := p.ensureConnected(); err != nil {
 		return 0, err
 	}
-	rows, err := p.db.QueryContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdent(table)))
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteTable(ctx, table)))
 	if err != nil {
 		return 0, err
 	}
@@ -283,6 +457,45 @@ func (p *Postgres) Count(ctx context.Context, table string) (int64, error) {
 	return 0, rows.Err()
 }
 
+func (p *Postgres) Find(ctx context.Context, table string, match database.Key, limit, offset int) ([]database.Row, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	if len(match) == 0 {
+		return p.Rows(ctx, table, limit, offset)
+	}
+	where, args, err := buildWhere(match, 1)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", quoteTable(ctx, table), where)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, offset)
+	}
+	return p.Query(ctx, query, args...)
+}
+
+func (p *Postgres) CountMatching(ctx context.Context, table string, match database.Key) (int64, error) {
+	if err := p.ensureConnected(); err != nil {
+		return 0, err
+	}
+	if len(match) == 0 {
+		return p.Count(ctx, table)
+	}
+	where, args, err := buildWhere(match, 1)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = p.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", quoteTable(ctx, table), where), args...).Scan(&count)
+	return count, err
+}
+
 func (p *Postgres) RowsColumns(ctx context.Context, table string, columns []string, limit, offset int) ([]database.Row, error) {
 	if err := p.ensureConnected(); err != nil {
 		return nil, err
@@ -291,7 +504,7 @@ func (p *Postgres) RowsColumns(ctx context.Context, table string, columns []stri
 	for i, c := range columns {
 		quotedCols[i] = quoteIdent(c)
 	}
-	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quotedCols, ", "), quoteIdent(table))
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quotedCols, ", "), quoteTable(ctx, table))
 	args := []any{}
 	if limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
@@ -308,8 +521,35 @@ func (p *Postgres) Insert(ctx context.Context, table string, data database.Row)
 	if err := p.ensureConnected(); err != nil {
 		return err
 	}
+	query, values, err := buildInsert(ctx, table, data)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, query, values...)
+	return err
+}
+
+func (p *Postgres) InsertReturning(ctx context.Context, table string, data database.Row) (database.Row, error) {
+	if err := p.ensureConnected(); err != nil {
+		return nil, err
+	}
+	query, values, err := buildInsert(ctx, table, data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.Query(ctx, query+" RETURNING *", values...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no row", table)
+	}
+	return rows[0], nil
+}
+
+func buildInsert(ctx context.Context, table string, data database.Row) (string, []any, error) {
 	if len(data) == 0 {
-		return fmt.Errorf("no data to insert into %s", table)
+		return "", nil, fmt.Errorf("no data to insert into %s", table)
 	}
 	keys := orderedKeys(data)
 	columns := make([]string, len(keys))
@@ -320,9 +560,8 @@ func (p *Postgres) Insert(ctx context.Context, table string, data database.Row)
 		placeholders[i] = fmt.Sprintf("$%d", i+1)
 		values[i] = data[key]
 	}
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
-	_, err := p.db.ExecContext(ctx, query, values...)
-	return err
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteTable(ctx, table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	return query, values, nil
 }
 
 func (p *Postgres) Update(ctx context.Context, table string, key database.Key, data database.Row) error {
@@ -351,7 +590,7 @@ func (p *Postgres) Update(ctx context.Context, table string, key database.Key, d
 	}
 	args = append(args, whereArgs...)
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteIdent(table), strings.Join(setClauses, ", "), where)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteTable(ctx, table), strings.Join(setClauses, ", "), where)
 	_, err = p.db.ExecContext(ctx, query, args...)
 	return err
 }
@@ -367,23 +606,61 @@ func (p *Postgres) Delete(ctx context.Context, table string, key database.Key) e
 	if err != nil {
 		return err
 	}
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s", quoteIdent(table), where)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", quoteTable(ctx, table), where)
 	_, err = p.db.ExecContext(ctx, query, args...)
 	return err
 }
 
+// Exec runs query through pgx directly so that the command tag can be reported to the
+// ExecStats in ctx.
 func (p *Postgres) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	if err := p.ensureConnected(); err != nil {
 		return nil, err
 	}
-	return p.db.ExecContext(ctx, query, args...)
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var tag pgconn.CommandTag
+	err = conn.Raw(func(driverConn any) error {
+		var err error
+		tag, err = driverConn.(*stdlib.Conn).Conn().Exec(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats := database.StatsFromContext(ctx); stats != nil {
+		stats.CommandTag = tag.String()
+	}
+	return driver.RowsAffected(tag.RowsAffected()), nil
+}
+
+func (p *Postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
 }
 
 func (p *Postgres) Query(ctx context.Context, query string, args ...any) ([]database.Row, error) {
 	if err := p.ensureConnected(); err != nil {
 		return nil, err
 	}
-	rows, err := p.db.QueryContext(ctx, query, args...)
+	var q interface {
+		QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	} = p.db
+	if database.ReadOnlyFromContext(ctx) {
+		// A session can turn default_transaction_read_only off again, so read-only queries
+		// run in their own READ ONLY transaction. Rolling it back also undoes any SET.
+		tx, err := p.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -394,8 +671,15 @@ func (p *Postgres) Query(ctx context.Context, query string, args ...any) ([]data
 		return nil, err
 	}
 
+	limit := database.RowLimitFromContext(ctx)
+	stats := database.StatsFromContext(ctx)
+	stats.SetColumns(columns)
 	var results []database.Row
 	for rows.Next() {
+		stats.FirstRowAt(start, time.Now())
+		if limit.Full(len(results)) {
+			break
+		}
 		values := make([]any, len(columns))
 		destinations := make([]any, len(columns))
 		for i := range values {
@@ -418,6 +702,20 @@ func (p *Postgres) Query(ctx context.Context, query string, args ...any) ([]data
 	return results, rows.Err()
 }
 
+// execDDL runs schema statements, or only records them when ctx carries a dry-run plan.
+func (p *Postgres) execDDL(ctx context.Context, stmts ...string) error {
+	if plan := database.PlanFromContext(ctx); plan != nil {
+		plan.Add(stmts...)
+		return nil
+	}
+	for _, stmt := range stmts {
+		if _, err := p.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *Postgres) ensureConnected() error {
 	if p.db == nil {
 		return database.ErrNotConnected
@@ -439,7 +737,8 @@ func buildWhere(key database.Key, startParamIndex int) (string, []any, error) {
 	return strings.Join(clauses, " AND "), args, nil
 }
 
-func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists bool) (string, error) {
+// buildCreateTableSQL renders a CREATE TABLE statement for a table in schema. Extra table constraints are appended after the columns.
+func buildCreateTableSQL(schema, name string, columns []database.ColumnDef, ifNotExists bool, constraints ...string) (string, error) {
 	if strings.TrimSpace(name) == "" {
 		return "", fmt.Errorf("table name is required")
 	}
@@ -455,7 +754,7 @@ func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists
 	var defs []string
 	var pkCols []string
 	for _, col := range columns {
-		def, err := buildColumnDefinition(col, pkCount == 1 && col.PrimaryKey)
+		def, err := buildColumnDefinition(schema, col, pkCount == 1 && col.PrimaryKey)
 		if err != nil {
 			return "", err
 		}
@@ -467,15 +766,18 @@ func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists
 	if len(pkCols) > 1 {
 		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pkCols, ", ")))
 	}
+	defs = append(defs, constraints...)
 	stmt := "CREATE TABLE "
 	if ifNotExists {
 		stmt += "IF NOT EXISTS "
 	}
-	stmt += fmt.Sprintf("%s (%s)", quoteIdent(name), strings.Join(defs, ", "))
+	stmt += fmt.Sprintf("%s.%s (%s)", quoteIdent(schema), quoteIdent(name), strings.Join(defs, ", "))
 	return stmt, nil
 }
 
-func buildColumnDefinition(col database.ColumnDef, allowInlinePK bool) (string, error) {
+// buildColumnDefinition renders a column of a table in schema; foreign keys without a
+// RefSchema point into the same schema.
+func buildColumnDefinition(schema string, col database.ColumnDef, allowInlinePK bool) (string, error) {
 	if strings.TrimSpace(col.Name) == "" || strings.TrimSpace(col.Type) == "" {
 		return "", fmt.Errorf("column name and type are required")
 	}
@@ -489,9 +791,42 @@ func buildColumnDefinition(col database.ColumnDef, allowInlinePK bool) (string,
 	if col.PrimaryKey && allowInlinePK {
 		parts = append(parts, "PRIMARY KEY")
 	}
+	for _, fk := range col.ForeignKeys {
+		references, err := buildReferences(schema, fk)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, references)
+	}
 	return strings.Join(parts, " "), nil
 }
 
+// buildReferences renders a column-level REFERENCES clause for a single-column foreign key
+// of a table in schema.
+func buildReferences(schema string, fk database.ForeignKey) (string, error) {
+	if err := fk.OnUpdate.Check(); err != nil {
+		return "", err
+	}
+	if err := fk.OnDelete.Check(); err != nil {
+		return "", err
+	}
+	refSchema := fk.RefSchema
+	if refSchema == "" {
+		refSchema = schema
+	}
+	clause := "REFERENCES " + quoteIdent(refSchema) + "." + quoteIdent(fk.RefTable)
+	if fk.ToCol != "" {
+		clause += fmt.Sprintf(" (%s)", quoteIdent(fk.ToCol))
+	}
+	if fk.OnUpdate != "" {
+		clause += " ON UPDATE " + string(fk.OnUpdate)
+	}
+	if fk.OnDelete != "" {
+		clause += " ON DELETE " + string(fk.OnDelete)
+	}
+	return clause, nil
+}
+
 func orderedKeys(m map[string]any) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -501,6 +836,19 @@ func orderedKeys(m map[string]any) []string {
 	return keys
 }
 
+// schemaOf returns the schema carried by ctx, defaulting to public.
+func schemaOf(ctx context.Context) string {
+	if schema := database.SchemaFromContext(ctx); schema != "" {
+		return schema
+	}
+	return defaultSchema
+}
+
+// quoteTable returns table qualified with the schema carried by ctx.
+func quoteTable(ctx context.Context, table string) string {
+	return quoteIdent(schemaOf(ctx)) + "." + quoteIdent(table)
+}
+
 func quoteIdent(name string) string {
 	escaped := strings.ReplaceAll(name, `"`, `""`)
 	return `"` + escaped + `"`
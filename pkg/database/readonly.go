@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMultipleStatements is returned for read-only queries holding more than one statement,
+// since a later statement could switch the session back to writing.
+var ErrMultipleStatements = errors.New("read-only queries must be a single statement")
+
+type readOnlyKey struct{}
+
+// WithReadOnly returns a context whose Query calls cannot write: PostgreSQL runs them in a
+// read-only transaction that is rolled back afterwards, so neither data nor session settings
+// change, and SQLite runs them on a connection with PRAGMA query_only set. This holds even
+// for functions with side effects called from a SELECT.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// ReadOnlyFromContext reports whether ctx asks for read-only queries.
+func ReadOnlyFromContext(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
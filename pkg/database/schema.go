@@ -0,0 +1,17 @@
+package database
+
+import "context"
+
+type schemaKey struct{}
+
+// WithSchema returns a context whose table calls address tables in schema instead of the
+// driver's default one. Drivers without schemas ignore it.
+func WithSchema(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, schemaKey{}, schema)
+}
+
+// SchemaFromContext returns the schema carried by ctx, or "" for the driver's default.
+func SchemaFromContext(ctx context.Context) string {
+	schema, _ := ctx.Value(schemaKey{}).(string)
+	return schema
+}
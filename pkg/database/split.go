@@ -0,0 +1,233 @@
+package database
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Statement is one statement of a script, with the position of its first character.
+type Statement struct {
+	SQL    string `json:"sql"`
+	Offset int    `json:"offset"` // Byte offset in the script.
+	Line   int    `json:"line"`   // 1-based.
+	Column int    `json:"column"` // 1-based, in characters.
+}
+
+// SplitStatements splits script at the semicolons ending its statements. Semicolons in
+// string literals, quoted identifiers, comments and the BEGIN ... END body of CREATE
+// TRIGGER statements do not end a statement. Empty statements are dropped.
+func SplitStatements(script string) []Statement {
+	var (
+		stmts []Statement
+		start = -1 // First byte of the current statement.
+	)
+	for i := 0; i < len(script); i++ {
+		if end := skipLiteral(script, i); end >= 0 {
+			if start < 0 && !strings.HasPrefix(script[i:], "--") && !strings.HasPrefix(script[i:], "/*") {
+				start = i
+			}
+			i = end
+			continue
+		}
+		switch c := script[i]; {
+		case c == ';':
+			if start >= 0 && !inCompoundBody(script[start:i]) {
+				stmts = append(stmts, newStatement(script, start, i))
+				start = -1
+			}
+		case start < 0 && !isSpace(c):
+			start = i
+		}
+	}
+	if start >= 0 {
+		stmts = append(stmts, newStatement(script, start, len(script)))
+	}
+	return stmts
+}
+
+func newStatement(script string, start, end int) Statement {
+	lineStart := strings.LastIndexByte(script[:start], '\n') + 1
+	return Statement{
+		SQL:    strings.TrimRight(script[start:end], " \t\r\n"),
+		Offset: start,
+		Line:   strings.Count(script[:start], "\n") + 1,
+		Column: utf8.RuneCountInString(script[lineStart:start]) + 1,
+	}
+}
+
+// inCompoundBody reports whether the CREATE statement text stops inside a BEGIN ... END
+// block, such as the body of an SQLite trigger, where semicolons separate inner statements.
+// CASE ... END expressions nest within such blocks.
+func inCompoundBody(text string) bool {
+	words := keywords(text)
+	if len(words) == 0 || words[0] != "CREATE" {
+		return false
+	}
+	depth := 0
+	for _, word := range words {
+		switch word {
+		case "BEGIN", "CASE":
+			depth++
+		case "END":
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// keywords returns the upper-cased words of text outside literals and comments.
+func keywords(text string) []string {
+	var words []string
+	for i := 0; i < len(text); i++ {
+		if end := skipLiteral(text, i); end >= 0 {
+			i = end
+			continue
+		}
+		if !isParamStart(text[i]) || (i > 0 && (isParamChar(text[i-1]) || text[i-1] == ':' || text[i-1] == '@')) {
+			continue
+		}
+		end := i + 1
+		for end < len(text) && isParamChar(text[end]) {
+			end++
+		}
+		words = append(words, strings.ToUpper(text[i:end]))
+		i = end - 1
+	}
+	return words
+}
+
+// Keywords returns the upper-cased words of the statement outside literals, quoted
+// identifiers and comments.
+func (s Statement) Keywords() []string {
+	return keywords(s.SQL)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// ReturnsRows reports whether the statement produces a result set: queries, and data
+// changes with a RETURNING clause. It only chooses between running the statement as a
+// query or an exec; use Writes to decide whether it may run at all.
+func (s Statement) ReturnsRows() bool {
+	words := keywords(s.SQL)
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "SELECT", "VALUES", "TABLE", "PRAGMA", "EXPLAIN", "SHOW":
+		return true
+	}
+	if containsWord(words, "RETURNING") {
+		return true
+	}
+	return words[0] == "WITH" && !changesData(words)
+}
+
+// Writes reports whether running the statement may change data, the schema or the session:
+// anything but plain queries. Data changes with a RETURNING clause, data changes inside
+// WITH, SELECT ... INTO, EXPLAIN ANALYZE of a data change and PRAGMA assignments count as
+// writes even though they return rows. Unknown statements are writes, and text holding
+// several statements writes if any of them does. Being a keyword check, it cannot see
+// functions with side effects called from a query: use it to reject statements early, and
+// run the rest with WithReadOnly.
+func (s Statement) Writes() bool {
+	if stmts := SplitStatements(s.SQL); len(stmts) > 1 {
+		for _, stmt := range stmts {
+			if stmt.Writes() {
+				return true
+			}
+		}
+		return false
+	}
+	words := keywords(s.SQL)
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "SELECT":
+		return containsWord(words, "INTO")
+	case "VALUES", "TABLE", "SHOW":
+		return false
+	case "WITH":
+		return changesData(words)
+	case "EXPLAIN":
+		return containsWord(words, "ANALYZE") && changesData(words)
+	case "PRAGMA":
+		// PRAGMA name = value and PRAGMA name(value) set the pragma, except for the
+		// pragmas whose argument only names what to report on.
+		var name string
+		if len(words) > 1 {
+			name = words[1]
+		}
+		if len(words) > 2 && hasByte(s.SQL, '.') {
+			name = words[2] // PRAGMA schema.name
+		}
+		if actingPragmas[name] || hasByte(s.SQL, '=') {
+			return true
+		}
+		return hasByte(s.SQL, '(') && !reportingPragmas[name]
+	}
+	return true
+}
+
+// ControlsSession reports whether the statement controls a transaction or changes settings
+// of the session that runs it, such as BEGIN, ROLLBACK, SAVEPOINT, SET and RESET. Its effect
+// reaches later statements only if they run on the same connection.
+func (s Statement) ControlsSession() bool {
+	words := keywords(s.SQL)
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT", "SAVEPOINT", "RELEASE",
+		"PREPARE", "DEALLOCATE", "SET", "RESET", "DISCARD":
+		return true
+	}
+	return false
+}
+
+// reportingPragmas take an argument that selects what they report instead of a new value.
+var reportingPragmas = map[string]bool{
+	"TABLE_INFO": true, "TABLE_XINFO": true, "TABLE_LIST": true, "INDEX_LIST": true,
+	"INDEX_INFO": true, "INDEX_XINFO": true, "FOREIGN_KEY_LIST": true,
+	"FOREIGN_KEY_CHECK": true, "INTEGRITY_CHECK": true, "QUICK_CHECK": true,
+}
+
+// actingPragmas change the database or connection even without an argument.
+var actingPragmas = map[string]bool{
+	"OPTIMIZE": true, "INCREMENTAL_VACUUM": true, "WAL_CHECKPOINT": true, "SHRINK_MEMORY": true,
+}
+
+func changesData(words []string) bool {
+	for _, word := range words {
+		switch word {
+		case "INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE":
+			return true
+		}
+	}
+	return false
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// hasByte reports whether c occurs in text outside literals and comments.
+func hasByte(text string, c byte) bool {
+	for i := 0; i < len(text); i++ {
+		if end := skipLiteral(text, i); end >= 0 {
+			i = end
+			continue
+		}
+		if text[i] == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `SELECT 1; SELECT ';' AS "a;b"; -- done;
+/* block; */ INSERT INTO t VALUES ($$x;y$$)
+;;
+CREATE TRIGGER tr AFTER INSERT ON t BEGIN
+  UPDATE t SET n = CASE WHEN n > 1 THEN 1 ELSE 2 END;
+  DELETE FROM u;
+END;
+  héllo; SELECT 2 -- trailing`
+	var got []Statement
+	for _, stmt := range SplitStatements(script) {
+		got = append(got, Statement{SQL: stmt.SQL, Line: stmt.Line, Column: stmt.Column})
+	}
+	want := []Statement{
+		{SQL: "SELECT 1", Line: 1, Column: 1},
+		{SQL: `SELECT ';' AS "a;b"`, Line: 1, Column: 11},
+		{SQL: "INSERT INTO t VALUES ($$x;y$$)", Line: 2, Column: 14},
+		{SQL: "CREATE TRIGGER tr AFTER INSERT ON t BEGIN\n  UPDATE t SET n = CASE WHEN n > 1 THEN 1 ELSE 2 END;\n  DELETE FROM u;\nEND", Line: 4, Column: 1},
+		{SQL: "héllo", Line: 8, Column: 3},
+		{SQL: "SELECT 2 -- trailing", Line: 8, Column: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected statements:\n got %q\nwant %q", got, want)
+	}
+	if stmts := SplitStatements(script); script[stmts[2].Offset:stmts[2].Offset+6] != "INSERT" {
+		t.Fatalf("unexpected offset %d", stmts[2].Offset)
+	}
+}
+
+func TestStatementWrites(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"select 1":                                               false,
+		"WITH x AS (SELECT 1) SELECT * FROM x":                   false,
+		"SHOW search_path":                                       false,
+		"PRAGMA table_info(t)":                                   false,
+		"PRAGMA main.index_list('t')":                            false,
+		"PRAGMA foreign_keys":                                    false,
+		"EXPLAIN SELECT * FROM t":                                false,
+		"SELECT 'delete' FROM t":                                 false,
+		"DELETE FROM t WHERE id = 3 RETURNING id":                true,
+		"WITH x AS (DELETE FROM t RETURNING id) SELECT * FROM x": true,
+		"INSERT INTO t VALUES (1)":                               true,
+		"SELECT * INTO copy FROM t":                              true,
+		"EXPLAIN ANALYZE DELETE FROM t":                          true,
+		"PRAGMA query_only = 0":                                  true,
+		"pragma main.journal_mode=DELETE":                        true,
+		"PRAGMA journal_mode(WAL)":                               true,
+		"PRAGMA optimize":                                        true,
+		"SET default_transaction_read_only = off":                true,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY)":                true,
+		"ATTACH DATABASE 'x.db' AS x":                            true,
+		"SELECT 1; SELECT 2":                                     false,
+		"SELECT 1; PRAGMA query_only = 0; SELECT 2":              true,
+	} {
+		if got := (Statement{SQL: sql}).Writes(); got != want {
+			t.Errorf("%s: got %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestStatementControlsSession(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"BEGIN":                        true,
+		"begin transaction":            true,
+		"START TRANSACTION READ WRITE": true,
+		"ROLLBACK TO SAVEPOINT a":      true,
+		"COMMIT":                       true,
+		"SET search_path TO other":     true,
+		"RESET ROLE":                   true,
+		"UPDATE t SET note = 'x'":      false,
+		"SELECT 'begin'":               false,
+		"CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT 1; END": false,
+	} {
+		if got := (Statement{SQL: sql}).ControlsSession(); got != want {
+			t.Errorf("%s: got %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestStatementReturnsRows(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"select 1":                                true,
+		"(SELECT 1) UNION SELECT 2":               true,
+		"WITH x AS (SELECT 1) SELECT * FROM x":    true,
+		"WITH x AS (SELECT 1) DELETE FROM t":      false,
+		"INSERT INTO t VALUES (1) RETURNING id":   true,
+		"UPDATE t SET note = 'returning'":         false,
+		"PRAGMA table_info(t)":                    true,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY)": false,
+		"DELETE FROM t WHERE note = 'select'":     false,
+	} {
+		if got := (Statement{SQL: sql}).ReturnsRows(); got != want {
+			t.Errorf("%s: got %v, want %v", sql, got, want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+)
+
+const maxCandidateColumns = 6
+
+var (
+	// tableRef matches "FROM t", "JOIN t AS a" and "JOIN t a"; the alias group may catch a
+	// keyword, which aliasOrKeyword filters out.
+	tableRef = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+("[^"]+"|[\w.]+)(?:\s+(?:AS\s+)?("[^"]+"|\w+))?`)
+	orderBy  = regexp.MustCompile(`(?is)\bORDER\s+BY\s+(.+?)(?:\bLIMIT\b|\bOFFSET\b|;|$)`)
+	keywords = map[string]bool{
+		"WHERE": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "CROSS": true,
+		"FULL": true, "NATURAL": true, "ON": true, "USING": true, "GROUP": true, "ORDER": true, "LIMIT": true,
+		"HAVING": true, "WINDOW": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "SET": true,
+	}
+)
+
+// SuggestIndexes follows the approach of the sqlite3 shell's .expert command: the schema,
+// without data, is copied into a scratch in-memory database, candidate indexes on the columns
+// the query mentions are created there one at a time, and a candidate is suggested when
+// re-planning the query shows it replacing a full table scan or a temporary b-tree sort.
+// Table statistics are not copied, so the planner judges candidates on defaults.
+func (s *SQLite) SuggestIndexes(ctx context.Context, query string, args ...any) ([]database.IndexSuggestion, error) {
+	if err := s.ensureConnected(); err != nil {
+		return nil, err
+	}
+	before, err := explainQueryPlan(ctx, s.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	aliases := tableAliases(query)
+	scanned, sorts := planProblems(before, aliases)
+	if len(scanned) == 0 && sorts == 0 {
+		return []database.IndexSuggestion{}, nil
+	}
+
+	scratch, err := s.scratchSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.Close()
+
+	tail := query
+	if i := strings.Index(strings.ToUpper(query), "FROM"); i >= 0 {
+		tail = query[i:]
+	}
+	suggestions := []database.IndexSuggestion{}
+	for _, table := range uniqueTables(aliases) {
+		cols, err := s.Columns(ctx, table)
+		if err != nil {
+			continue
+		}
+		mentioned := mentionedColumns(tail, cols)
+		ordered := orderColumns(query, cols)
+		if len(mentioned) == 0 || (!scanned[table] && len(ordered) == 0) {
+			continue
+		}
+
+		var best *database.IndexSuggestion
+		bestScore := 0
+		for _, candidate := range candidateIndexes(mentioned, ordered) {
+			def := database.IndexDef{Columns: candidate}
+			score, reason, err := tryIndex(ctx, scratch, table, def, query, args, scanned[table], sorts)
+			if err != nil {
+				return nil, err
+			}
+			if score > bestScore || (score == bestScore && score > 0 && len(candidate) < len(best.Index.Columns)) {
+				stmt, _ := buildCreateIndex(table, def)
+				best = &database.IndexSuggestion{Table: table, Index: def, Statement: stmt, Reason: reason, Verified: true}
+				bestScore = score
+			}
+		}
+		if best != nil {
+			suggestions = append(suggestions, *best)
+		}
+	}
+	return suggestions, nil
+}
+
+// scratchSchema opens an in-memory database holding the tables, indexes and views of s.
+func (s *SQLite) scratchSchema(ctx context.Context) (*sql.DB, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' AND type IN ('table', 'index', 'view')
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END`)
+	if err != nil {
+		return nil, err
+	}
+	var stmts []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	scratch, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	scratch.SetMaxOpenConns(1) // Every connection would get its own empty :memory: database.
+	for _, stmt := range stmts {
+		// Objects the scratch database cannot recreate, such as virtual tables of missing
+		// modules, are left out; queries on them are not planned either way.
+		_, _ = scratch.ExecContext(ctx, stmt)
+	}
+	return scratch, nil
+}
+
+// tryIndex creates def in the scratch database, re-plans query and drops it again. The score
+// is 2 when the index replaces the full scan of table, plus 1 when it removes a sort.
+func tryIndex(ctx context.Context, scratch *sql.DB, table string, def database.IndexDef, query string, args []any, scanned bool, sorts int) (int, string, error) {
+	def.Name = "advisor_candidate" // Names starting with "sqlite_" are reserved.
+	stmt, err := buildCreateIndex(table, def)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := scratch.ExecContext(ctx, stmt); err != nil {
+		return 0, "", nil // Not an indexable table, e.g. a view.
+	}
+	defer scratch.ExecContext(ctx, "DROP INDEX "+quoteIdent(def.Name))
+
+	after, err := explainQueryPlan(ctx, scratch, query, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	aliases := tableAliases(query)
+	used := false
+	after.Walk(func(n *database.PlanNode) {
+		used = used || (aliases[n.Relation] == table && n.Index == def.Name)
+	})
+	if !used {
+		return 0, "", nil
+	}
+	stillScanned, sortsAfter := planProblems(after, aliases)
+
+	score := 0
+	var reasons []string
+	if scanned && !stillScanned[table] {
+		score += 2
+		reasons = append(reasons, "avoids a full scan of "+table)
+	}
+	if sortsAfter < sorts {
+		score++
+		reasons = append(reasons, "avoids a temporary b-tree sort")
+	}
+	return score, strings.Join(reasons, " and "), nil
+}
+
+// planProblems returns the tables the plan scans in full and the number of sorts it needs.
+func planProblems(plan *database.QueryPlan, aliases map[string]string) (map[string]bool, int) {
+	scanned := make(map[string]bool)
+	sorts := 0
+	plan.Walk(func(n *database.PlanNode) {
+		if n.FullScan {
+			if table, ok := aliases[n.Relation]; ok {
+				scanned[table] = true
+			}
+		}
+		if n.Sort {
+			sorts++
+		}
+	})
+	return scanned, sorts
+}
+
+// tableAliases maps every table name and alias in the FROM and JOIN clauses of query to
+// its table.
+func tableAliases(query string) map[string]string {
+	aliases := make(map[string]string)
+	for _, m := range tableRef.FindAllStringSubmatch(query, -1) {
+		table := unquote(m[1])
+		if keywords[strings.ToUpper(table)] || strings.HasPrefix(table, "(") {
+			continue
+		}
+		aliases[table] = table
+		if alias := unquote(m[2]); alias != "" && !keywords[strings.ToUpper(alias)] {
+			aliases[alias] = table
+		}
+	}
+	return aliases
+}
+
+func uniqueTables(aliases map[string]string) []string {
+	seen := make(map[string]bool)
+	var tables []string
+	for _, table := range aliases {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// mentionedColumns returns the columns of cols named in text, in order of first mention.
+func mentionedColumns(text string, cols []database.Column) []string {
+	type mention struct {
+		name string
+		pos  int
+	}
+	var found []mention
+	for _, col := range cols {
+		re := regexp.MustCompile(`(?i)(?:^|[^\w])"?` + regexp.QuoteMeta(col.Name) + `"?(?:[^\w]|$)`)
+		if loc := re.FindStringIndex(text); loc != nil {
+			found = append(found, mention{col.Name, loc[0]})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+	names := make([]string, len(found))
+	for i, m := range found {
+		names[i] = m.name
+	}
+	return names
+}
+
+// orderColumns returns the ORDER BY terms of query that are plain columns of cols.
+func orderColumns(query string, cols []database.Column) []string {
+	m := orderBy.FindStringSubmatch(query)
+	if m == nil {
+		return nil
+	}
+	known := make(map[string]string, len(cols))
+	for _, col := range cols {
+		known[strings.ToLower(col.Name)] = col.Name
+	}
+	var names []string
+	for _, term := range strings.Split(m[1], ",") {
+		fields := strings.Fields(term)
+		if len(fields) == 0 {
+			continue
+		}
+		name := fields[0]
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		col, ok := known[strings.ToLower(unquote(name))]
+		if !ok {
+			return nil // An expression or another table's column: no single index can serve it.
+		}
+		names = append(names, col)
+	}
+	return names
+}
+
+// candidateIndexes lists the column sets to try: each mentioned column alone, all of them,
+// and the filter columns followed by the ORDER BY columns.
+func candidateIndexes(mentioned, ordered []string) [][]string {
+	if len(mentioned) > maxCandidateColumns {
+		mentioned = mentioned[:maxCandidateColumns]
+	}
+	var candidates [][]string
+	for _, col := range mentioned {
+		candidates = append(candidates, []string{col})
+	}
+	if len(mentioned) > 1 {
+		candidates = append(candidates, mentioned)
+	}
+	if len(ordered) > 0 {
+		isOrdered := make(map[string]bool, len(ordered))
+		for _, col := range ordered {
+			isOrdered[col] = true
+		}
+		var filterThenOrder []string
+		for _, col := range mentioned {
+			if !isOrdered[col] {
+				filterThenOrder = append(filterThenOrder, col)
+			}
+		}
+		filterThenOrder = append(filterThenOrder, ordered...)
+		candidates = append(candidates, ordered, filterThenOrder)
+	}
+	return candidates
+}
+
+func unquote(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return name
+}
@@ -0,0 +1,563 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sqlite-gui/pkg/database"
+)
+
+// queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// foreignKeyGroup is one FOREIGN KEY constraint as reported by PRAGMA foreign_key_list.
+// Composite constraints span several rows sharing the same id.
+type foreignKeyGroup struct {
+	refTable string
+	from     []string
+	to       []string
+	onUpdate database.ForeignKeyAction
+	onDelete database.ForeignKeyAction
+}
+
+func (s *SQLite) AlterColumn(ctx context.Context, table, column string, def database.ColumnDef) error {
+	if err := s.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" || strings.TrimSpace(column) == "" {
+		return fmt.Errorf("table and column are required")
+	}
+	if def.Name == "" {
+		def.Name = column
+	}
+	current, err := s.Columns(ctx, table)
+	if err != nil {
+		return err
+	}
+	columns := make([]database.ColumnDef, 0, len(current))
+	found := false
+	for _, col := range current {
+		if col.Name == column {
+			columns = append(columns, def)
+			found = true
+			continue
+		}
+		columns = append(columns, col.Definition())
+	}
+	if !found {
+		return fmt.Errorf("column %s not found in %s", column, table)
+	}
+	var renames map[string]string
+	if def.Name != column {
+		renames = map[string]string{column: def.Name}
+	}
+	return s.rebuildTable(ctx, table, columns, renames)
+}
+
+func (s *SQLite) AlterTable(ctx context.Context, table string, columns []database.ColumnDef) error {
+	if err := s.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" {
+		return fmt.Errorf("table name is required")
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+	return s.rebuildTable(ctx, table, columns, nil)
+}
+
+// rebuildTable reshapes table to match columns using the generalized ALTER TABLE procedure
+// described at https://www.sqlite.org/lang_altertable.html#otheralter: create the new table,
+// copy the data, drop the old table, rename the new one and recreate indexes and triggers,
+// all inside one transaction that is verified with PRAGMA foreign_key_check before commit.
+//
+// renames maps old column names to new ones and is applied before the rebuild so that
+// indexes and triggers follow the renamed columns. Data is copied for every column whose
+// (renamed) name exists in both the old and new table. UNIQUE constraints, inline or not,
+// are kept as table constraints unless one of their columns is dropped. An AUTOINCREMENT
+// key is kept, together with its sqlite_sequence value, so deleted ids are not handed out
+// again. Tables with CHECK constraints, collations, generated columns or WITHOUT ROWID
+// cannot be rebuilt without losing them and are refused; a dry-run plan warns instead.
+func (s *SQLite) rebuildTable(ctx context.Context, table string, columns []database.ColumnDef, renames map[string]string) (err error) {
+	existing, err := s.Columns(ctx, table)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return fmt.Errorf("table %s not found", table)
+	}
+	unsupported, err := unsupportedFeatures(ctx, s.db, table)
+	if err != nil {
+		return err
+	}
+	plan := database.PlanFromContext(ctx)
+	if len(unsupported) > 0 {
+		if plan == nil {
+			return fmt.Errorf("cannot rebuild %s without losing its %s; change it with SQL instead", table, strings.Join(unsupported, ", "))
+		}
+		plan.Warn("%s has %s, which the rebuild cannot carry over: the change will be refused", table, strings.Join(unsupported, ", "))
+	}
+	oldCols := make(map[string]bool, len(existing))
+	for _, col := range existing {
+		name := col.Name
+		if renamed, ok := renames[name]; ok {
+			name = renamed
+		}
+		oldCols[name] = true
+	}
+	groups, err := s.foreignKeyGroups(ctx, table)
+	if err != nil {
+		return err
+	}
+	autoincrement, seq, err := autoincrementOf(ctx, s.db, table)
+	if err != nil {
+		return err
+	}
+
+	newCols := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		newCols[col.Name] = true
+	}
+	constraints, composite := compositeForeignKeys(groups, renames, newCols)
+	unique, err := uniqueConstraints(ctx, s.db, table, renames, newCols)
+	if err != nil {
+		return err
+	}
+	constraints = append(constraints, unique...)
+	defs := make([]database.ColumnDef, len(columns))
+	var copyCols []string
+	for i, col := range columns {
+		if strings.TrimSpace(col.Type) == "" {
+			col.Type = "BLOB" // Untyped columns have BLOB affinity.
+		}
+		col.ForeignKeys = withoutComposite(col.Name, col.ForeignKeys, composite)
+		defs[i] = col
+		if oldCols[col.Name] {
+			copyCols = append(copyCols, quoteIdent(col.Name))
+		}
+	}
+
+	tmpName := "_sqlite_gui_new_" + table
+	create, err := createTableSQL(tmpName, defs, false, autoincrement, constraints...)
+	if err != nil {
+		if autoincrement {
+			return fmt.Errorf("table %s uses AUTOINCREMENT: %w", table, err)
+		}
+		return err
+	}
+
+	renameStmts := make([]string, 0, len(renames))
+	for from, to := range renames {
+		renameStmts = append(renameStmts, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteIdent(table), quoteIdent(from), quoteIdent(to)))
+	}
+
+	if plan != nil {
+		schema, err := schemaObjects(ctx, s.db, table)
+		if err != nil {
+			return err
+		}
+		plan.Add("BEGIN")
+		plan.Add(renameStmts...)
+		plan.Add(rebuildStatements(table, tmpName, create, copyCols, schema)...)
+		plan.Add(restoreSequence(table, seq)...)
+		plan.Add(fmt.Sprintf("PRAGMA foreign_key_check(%s)", quoteIdent(table)), "COMMIT")
+		if len(renameStmts) > 0 && len(schema) > 0 {
+			plan.Warn("indexes and triggers on %s are recreated as rewritten by the column rename", table)
+		}
+		return nil
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Foreign key enforcement cannot be toggled inside a transaction, so it is switched
+	// off on the pinned connection for the duration of the rebuild.
+	var fkEnabled int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fkEnabled); err != nil {
+		return err
+	}
+	if fkEnabled == 1 {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA foreign_keys = ON")
+	}
+	// Keep views and triggers that reference the table from being rewritten to the temporary name.
+	if _, err := conn.ExecContext(ctx, "PRAGMA legacy_alter_table = ON"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA legacy_alter_table = OFF")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, stmt := range renameStmts {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	schema, err := schemaObjects(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	stmts := append(rebuildStatements(table, tmpName, create, copyCols, schema), restoreSequence(table, seq)...)
+	for _, stmt := range stmts {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w (while running %q)", err, stmt)
+		}
+	}
+
+	// Checked even when enforcement is off, so a rebuild never leaves dangling references.
+	if err = foreignKeyCheck(ctx, tx, table); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebuildStatements lists the create, copy, drop, rename and schema restore steps of a rebuild.
+func rebuildStatements(table, tmpName, create string, copyCols, schema []string) []string {
+	stmts := []string{create}
+	if len(copyCols) > 0 {
+		list := strings.Join(copyCols, ", ")
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quoteIdent(tmpName), list, list, quoteIdent(table)))
+	}
+	stmts = append(stmts,
+		"DROP TABLE "+quoteIdent(table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(tmpName), quoteIdent(table)),
+	)
+	return append(stmts, schema...)
+}
+
+func (s *SQLite) foreignKeyGroups(ctx context.Context, table string) ([]foreignKeyGroup, error) {
+	query := fmt.Sprintf("PRAGMA foreign_key_list(%s)", quoteIdent(table))
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		groups []foreignKeyGroup
+		index  = make(map[int]int)
+	)
+	for rows.Next() {
+		var (
+			id, seq  int
+			refTbl   string
+			from     string
+			to       sql.NullString
+			onUpdate string
+			onDelete string
+			match    string
+		)
+		if err := rows.Scan(&id, &seq, &refTbl, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, err
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, foreignKeyGroup{
+				refTable: refTbl,
+				onUpdate: database.ForeignKeyAction(onUpdate),
+				onDelete: database.ForeignKeyAction(onDelete),
+			})
+		}
+		groups[i].from = append(groups[i].from, from)
+		groups[i].to = append(groups[i].to, to.String)
+	}
+	return groups, rows.Err()
+}
+
+// compositeForeignKeys renders table-level constraints for the multi-column foreign keys that
+// survive the rebuild and returns the single-column parts they cover, keyed by column name.
+func compositeForeignKeys(groups []foreignKeyGroup, renames map[string]string, keep map[string]bool) ([]string, map[string][]database.ForeignKey) {
+	var constraints []string
+	covered := make(map[string][]database.ForeignKey)
+	for _, g := range groups {
+		if len(g.from) < 2 {
+			continue
+		}
+		from := make([]string, len(g.from))
+		to := make([]string, 0, len(g.to))
+		complete := true
+		for i, col := range g.from {
+			if renamed, ok := renames[col]; ok {
+				col = renamed
+			}
+			// Parts of a composite key never stand on their own, even when the constraint is dropped.
+			covered[col] = append(covered[col], database.ForeignKey{RefTable: g.refTable, FromCol: g.from[i], ToCol: g.to[i]})
+			complete = complete && keep[col]
+			from[i] = quoteIdent(col)
+			if g.to[i] != "" {
+				to = append(to, quoteIdent(g.to[i]))
+			}
+		}
+		if !complete {
+			continue
+		}
+		ref := quoteIdent(g.refTable)
+		if len(to) == len(from) {
+			ref += fmt.Sprintf(" (%s)", strings.Join(to, ", "))
+		}
+		constraints = append(constraints, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s ON UPDATE %s ON DELETE %s",
+			strings.Join(from, ", "), ref, g.onUpdate, g.onDelete))
+	}
+	return constraints, covered
+}
+
+// withoutComposite drops the foreign keys of column that are already rendered as part of a composite constraint.
+func withoutComposite(column string, fks []database.ForeignKey, composite map[string][]database.ForeignKey) []database.ForeignKey {
+	covered := composite[column]
+	if len(covered) == 0 {
+		return fks
+	}
+	var result []database.ForeignKey
+	for _, fk := range fks {
+		skip := false
+		for _, c := range covered {
+			if fk.RefTable == c.RefTable && fk.ToCol == c.ToCol {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			result = append(result, fk)
+		}
+	}
+	return result
+}
+
+// schemaObjects returns the CREATE statements of the explicit indexes and triggers attached to table.
+func schemaObjects(ctx context.Context, q queryer, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, "SELECT sql FROM sqlite_master WHERE tbl_name = ? AND type IN ('index', 'trigger') AND sql IS NOT NULL ORDER BY type", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stmts []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, rows.Err()
+}
+
+// uniqueConstraints renders the UNIQUE constraints of table, whether declared inline or as
+// table constraints, as table constraints over the renamed columns. Constraints on a column
+// missing from keep are dropped along with it.
+func uniqueConstraints(ctx context.Context, q queryer, table string, renames map[string]string, keep map[string]bool) ([]string, error) {
+	rows, err := q.QueryContext(ctx, "SELECT name FROM pragma_index_list(?) WHERE origin = 'u' ORDER BY seq DESC", table)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []string
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var constraints []string
+	for _, index := range indexes {
+		rows, err := q.QueryContext(ctx, "SELECT name FROM pragma_index_info(?) ORDER BY seqno", index)
+		if err != nil {
+			return nil, err
+		}
+		var cols []string
+		complete := true
+		for rows.Next() {
+			var col string
+			if err := rows.Scan(&col); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if renamed, ok := renames[col]; ok {
+				col = renamed
+			}
+			complete = complete && keep[col]
+			cols = append(cols, quoteIdent(col))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if complete {
+			constraints = append(constraints, fmt.Sprintf("UNIQUE (%s)", strings.Join(cols, ", ")))
+		}
+	}
+	return constraints, nil
+}
+
+// unsupportedFeatures lists what a rebuild of table cannot carry over from its CREATE TABLE
+// statement: CHECK constraints, collations, generated columns and WITHOUT ROWID.
+func unsupportedFeatures(ctx context.Context, q queryer, table string) ([]string, error) {
+	var create sql.NullString
+	if err := queryRow(ctx, q, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", []any{table}, &create); err != nil {
+		return nil, err
+	}
+	var generated int
+	if err := queryRow(ctx, q, "SELECT count(*) FROM pragma_table_xinfo(?) WHERE hidden IN (2, 3)", []any{table}, &generated); err != nil {
+		return nil, err
+	}
+	words := database.Statement{SQL: create.String}.Keywords()
+	var features []string
+	if containsWord(words, "CHECK") {
+		features = append(features, "CHECK constraints")
+	}
+	if containsWord(words, "COLLATE") {
+		features = append(features, "collations")
+	}
+	if generated > 0 {
+		features = append(features, "generated columns")
+	}
+	for i := 0; i+1 < len(words); i++ {
+		if words[i] == "WITHOUT" && words[i+1] == "ROWID" {
+			features = append(features, "WITHOUT ROWID")
+			break
+		}
+	}
+	return features, nil
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignKeyCheck fails when rows of table reference missing parents or rows of other
+// tables reference missing rows of table.
+func foreignKeyCheck(ctx context.Context, q queryer, table string) error {
+	violations, err := foreignKeyViolations(ctx, q, table)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return errors.New("rebuild would violate foreign key constraints on " + table)
+	}
+	rows, err := q.QueryContext(ctx, `SELECT DISTINCT m.name FROM sqlite_master m, pragma_foreign_key_list(m.name) p
+		WHERE m.type = 'table' AND m.name <> ? AND p."table" = ? COLLATE NOCASE`, table, table)
+	if err != nil {
+		return err
+	}
+	var children []string
+	for rows.Next() {
+		var child string
+		if err := rows.Scan(&child); err != nil {
+			rows.Close()
+			return err
+		}
+		children = append(children, child)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, child := range children {
+		violations, err := foreignKeyViolations(ctx, q, child)
+		if err != nil {
+			return err
+		}
+		for _, parent := range violations {
+			if strings.EqualFold(parent, table) {
+				return fmt.Errorf("rebuild would break foreign keys from %s to %s", child, table)
+			}
+		}
+	}
+	return nil
+}
+
+// foreignKeyViolations returns the parent table of each row of table that PRAGMA
+// foreign_key_check reports.
+func foreignKeyViolations(ctx context.Context, q queryer, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("PRAGMA foreign_key_check(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var parents []string
+	for rows.Next() {
+		var (
+			child, parent string
+			rowid         sql.NullInt64
+			fkid          int
+		)
+		if err := rows.Scan(&child, &rowid, &parent, &fkid); err != nil {
+			return nil, err
+		}
+		parents = append(parents, parent)
+	}
+	return parents, rows.Err()
+}
+
+// autoincrementOf reports whether table was declared with an AUTOINCREMENT key and returns
+// its current sqlite_sequence value.
+func autoincrementOf(ctx context.Context, q queryer, table string) (bool, int64, error) {
+	var create sql.NullString
+	err := queryRow(ctx, q, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", []any{table}, &create)
+	if err != nil || !strings.Contains(strings.ToUpper(create.String), "AUTOINCREMENT") {
+		return false, 0, err
+	}
+	var seq int64
+	err = queryRow(ctx, q, "SELECT seq FROM sqlite_sequence WHERE name = ?", []any{table}, &seq)
+	return true, seq, err
+}
+
+// queryRow scans the first row of query into dest, leaving dest untouched when there is none.
+func queryRow(ctx context.Context, q queryer, query string, args []any, dest ...any) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// restoreSequence puts the sqlite_sequence value of an AUTOINCREMENT table back after a
+// rebuild, which only carries over the highest id still in the table.
+func restoreSequence(table string, seq int64) []string {
+	if seq == 0 {
+		return nil
+	}
+	name := "'" + strings.ReplaceAll(table, "'", "''") + "'"
+	return []string{
+		fmt.Sprintf("UPDATE sqlite_sequence SET seq = max(seq, %d) WHERE name = %s", seq, name),
+		fmt.Sprintf("INSERT INTO sqlite_sequence (name, seq) SELECT %s, %d WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = %s)", name, seq, name),
+	}
+}
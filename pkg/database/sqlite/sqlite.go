@@ -4,9 +4,12 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"sqlite-gui/pkg/database"
 
@@ -40,6 +43,31 @@ func (s *SQLite) Connect(ctx context.Context, conn string) error {
 	return nil
 }
 
+// ReadOnlyConn rewrites conn so that every connection opened from it rejects writes: the
+// database is opened with mode=ro, turning plain paths into file: URIs, and all connections
+// also run PRAGMA query_only. mode=ro cannot be undone from a session, unlike query_only.
+func ReadOnlyConn(conn string) string {
+	path, query, _ := strings.Cut(conn, "?")
+	if !strings.HasPrefix(path, "file:") {
+		path = "file:" + strings.NewReplacer("%", "%25", "#", "%23").Replace(path)
+	}
+	params := []string{"mode=ro"}
+	for _, param := range strings.Split(query, "&") {
+		if param != "" && !strings.HasPrefix(param, "mode=") {
+			params = append(params, param)
+		}
+	}
+	return path + "?" + strings.Join(append(params, "_pragma=query_only(1)"), "&")
+}
+
+// InMemory reports whether conn opens a private in-memory (or temporary) database, which a
+// second connection cannot see.
+func InMemory(conn string) bool {
+	path, query, _ := strings.Cut(conn, "?")
+	path = strings.TrimPrefix(path, "file:")
+	return path == "" || path == ":memory:" || strings.Contains("&"+query+"&", "&mode=memory&")
+}
+
 func (s *SQLite) Close() error {
 	if s.db == nil {
 		return nil
@@ -56,6 +84,14 @@ func (s *SQLite) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
 
+// Schemas reports the main database only; table calls ignore the schema in their context.
+func (s *SQLite) Schemas(ctx context.Context) ([]string, error) {
+	if err := s.ensureConnected(); err != nil {
+		return nil, err
+	}
+	return []string{"main"}, nil
+}
+
 func (s *SQLite) Tables(ctx context.Context) ([]string, error) {
 	if err := s.ensureConnected(); err != nil {
 		return nil, err
@@ -131,8 +167,7 @@ func (s *SQLite) CreateTable(ctx context.Context, name string, columns []databas
 	if err != nil {
 		return err
 	}
-	_, err = s.db.ExecContext(ctx, stmt)
-	return err
+	return s.execDDL(ctx, stmt)
 }
 
 func (s *SQLite) AddColumn(ctx context.Context, table string, column database.ColumnDef) error {
@@ -145,13 +180,12 @@ func (s *SQLite) AddColumn(ctx context.Context, table string, column database.Co
 	if column.PrimaryKey {
 		return fmt.Errorf("adding primary key columns via ALTER TABLE is not supported")
 	}
-	definition, err := buildColumnDefinition(column, false)
+	definition, err := buildColumnDefinition(column, "")
 	if err != nil {
 		return err
 	}
 	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(table), definition)
-	_, err = s.db.ExecContext(ctx, stmt)
-	return err
+	return s.execDDL(ctx, stmt)
 }
 
 func (s *SQLite) DropColumn(ctx context.Context, table, column string) error {
@@ -162,8 +196,59 @@ func (s *SQLite) DropColumn(ctx context.Context, table, column string) error {
 		return fmt.Errorf("table and column are required")
 	}
 	stmt := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(table), quoteIdent(column))
-	_, err := s.db.ExecContext(ctx, stmt)
-	return err
+	return s.execDDL(ctx, stmt)
+}
+
+func (s *SQLite) RenameTable(ctx context.Context, table, newName string) error {
+	if err := s.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" || strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("table and new name are required")
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(table), quoteIdent(newName))
+	return s.execDDL(ctx, stmt)
+}
+
+func (s *SQLite) RenameColumn(ctx context.Context, table, column, newName string) error {
+	if err := s.ensureConnected(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(table) == "" || strings.TrimSpace(column) == "" || strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("table, column and new name are required")
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteIdent(table), quoteIdent(column), quoteIdent(newName))
+	return s.execDDL(ctx, stmt)
+}
+
+// Dependents matches views and triggers by searching their SQL for the table (and column) name,
+// so it may report objects that merely mention a similarly named identifier.
+func (s *SQLite) Dependents(ctx context.Context, table, column string) ([]database.DependentObject, error) {
+	if err := s.ensureConnected(); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT type, name FROM sqlite_master
+		WHERE type IN ('view', 'trigger') AND name != ?1
+			AND (tbl_name = ?1 OR instr(lower(sql), lower(?1)) > 0)
+			AND (?2 = '' OR instr(lower(sql), lower(?2)) > 0)
+		ORDER BY type, name
+	`
+	rows, err := s.db.QueryContext(ctx, query, table, column)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []database.DependentObject
+	for rows.Next() {
+		var dep database.DependentObject
+		if err := rows.Scan(&dep.Type, &dep.Name); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
 }
 
 func (s *SQLite) DropTable(ctx context.Context, table string, ifExists bool) error {
@@ -178,8 +263,36 @@ func (s *SQLite) DropTable(ctx context.Context, table string, ifExists bool) err
 		stmt += "IF EXISTS "
 	}
 	stmt += quoteIdent(table)
-	_, err := s.db.ExecContext(ctx, stmt)
-	return err
+	return s.execDDL(ctx, stmt)
+}
+
+func (s *SQLite) CreateIndex(ctx context.Context, table string, index database.IndexDef) error {
+	if err := s.ensureConnected(); err != nil {
+		return err
+	}
+	stmt, err := buildCreateIndex(table, index)
+	if err != nil {
+		return err
+	}
+	return s.execDDL(ctx, stmt)
+}
+
+func buildCreateIndex(table string, index database.IndexDef) (string, error) {
+	if strings.TrimSpace(table) == "" {
+		return "", fmt.Errorf("table name is required")
+	}
+	if len(index.Columns) == 0 {
+		return "", fmt.Errorf("at least one index column is required")
+	}
+	cols := make([]string, len(index.Columns))
+	for i, col := range index.Columns {
+		cols[i] = quoteIdent(col)
+	}
+	stmt := "CREATE INDEX "
+	if index.Unique {
+		stmt = "CREATE UNIQUE INDEX "
+	}
+	return stmt + quoteIdent(index.IndexName(table)) + " ON " + quoteIdent(table) + " (" + strings.Join(cols, ", ") + ")", nil
 }
 
 func (s *SQLite) Rows(ctx context.Context, table string, limit, offset int) ([]database.Row, error) {
@@ -221,6 +334,48 @@ func (s *SQLite) Count(ctx context.Context, table string) (int64, error) {
 	return 0, rows.Err()
 }
 
+func (s *SQLite) Find(ctx context.Context, table string, match database.Key, limit, offset int) ([]database.Row, error) {
+	if err := s.ensureConnected(); err != nil {
+		return nil, err
+	}
+	if len(match) == 0 {
+		return s.Rows(ctx, table, limit, offset)
+	}
+	where, args, err := buildWhere(match)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", quoteIdent(table), where)
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	if offset > 0 {
+		if limit <= 0 {
+			query += " LIMIT -1"
+		}
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+	return s.Query(ctx, query, args...)
+}
+
+func (s *SQLite) CountMatching(ctx context.Context, table string, match database.Key) (int64, error) {
+	if err := s.ensureConnected(); err != nil {
+		return 0, err
+	}
+	if len(match) == 0 {
+		return s.Count(ctx, table)
+	}
+	where, args, err := buildWhere(match)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", quoteIdent(table), where), args...).Scan(&count)
+	return count, err
+}
+
 func (s *SQLite) RowsColumns(ctx context.Context, table string, columns []string, limit, offset int) ([]database.Row, error) {
 	if err := s.ensureConnected(); err != nil {
 		return nil, err
@@ -249,8 +404,35 @@ func (s *SQLite) Insert(ctx context.Context, table string, data database.Row) er
 	if err := s.ensureConnected(); err != nil {
 		return err
 	}
+	query, values, err := buildInsert(table, data)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query, values...)
+	return err
+}
+
+func (s *SQLite) InsertReturning(ctx context.Context, table string, data database.Row) (database.Row, error) {
+	if err := s.ensureConnected(); err != nil {
+		return nil, err
+	}
+	query, values, err := buildInsert(table, data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Query(ctx, query+" RETURNING *", values...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no row", table)
+	}
+	return rows[0], nil
+}
+
+func buildInsert(table string, data database.Row) (string, []any, error) {
 	if len(data) == 0 {
-		return fmt.Errorf("no data to insert into %s", table)
+		return "", nil, fmt.Errorf("no data to insert into %s", table)
 	}
 	keys := orderedKeys(data)
 	columns := make([]string, len(keys))
@@ -262,8 +444,7 @@ func (s *SQLite) Insert(ctx context.Context, table string, data database.Row) er
 		values[i] = data[key]
 	}
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
-	_, err := s.db.ExecContext(ctx, query, values...)
-	return err
+	return query, values, nil
 }
 
 func (s *SQLite) Update(ctx context.Context, table string, key database.Key, data database.Row) error {
@@ -316,11 +497,25 @@ func (s *SQLite) Exec(ctx context.Context, query string, args ...any) (sql.Resul
 	return s.db.ExecContext(ctx, query, args...)
 }
 
+func (s *SQLite) Placeholder(int) string {
+	return "?"
+}
+
 func (s *SQLite) Query(ctx context.Context, query string, args ...any) ([]database.Row, error) {
 	if err := s.ensureConnected(); err != nil {
 		return nil, err
 	}
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	var q queryer = s.db
+	if database.ReadOnlyFromContext(ctx) {
+		conn, release, err := s.readOnlySession(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		q = conn
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -331,8 +526,15 @@ func (s *SQLite) Query(ctx context.Context, query string, args ...any) ([]databa
 		return nil, err
 	}
 
+	limit := database.RowLimitFromContext(ctx)
+	stats := database.StatsFromContext(ctx)
+	stats.SetColumns(columns)
 	var results []database.Row
 	for rows.Next() {
+		stats.FirstRowAt(start, time.Now())
+		if limit.Full(len(results)) {
+			break
+		}
 		values := make([]any, len(columns))
 		destinations := make([]any, len(columns))
 		for i := range values {
@@ -355,6 +557,38 @@ func (s *SQLite) Query(ctx context.Context, query string, args ...any) ([]databa
 	return results, rows.Err()
 }
 
+// readOnlySession pins a connection for a read-only query and turns PRAGMA query_only on for
+// it, which also stops functions called from a SELECT from writing. The driver runs every
+// statement of a query, so query must be a single one. release restores the previous
+// setting, or discards the connection when that fails.
+func (s *SQLite) readOnlySession(ctx context.Context, query string) (*sql.Conn, func(), error) {
+	if len(database.SplitStatements(query)) > 1 {
+		return nil, nil, database.ErrMultipleStatements
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var was bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA query_only").Scan(&was); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	release := func() {
+		// The query's context may be cancelled already.
+		restore := fmt.Sprintf("PRAGMA query_only = %t", was)
+		if _, err := conn.ExecContext(context.Background(), restore); err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return conn, release, nil
+}
+
 func (s *SQLite) foreignKeys(ctx context.Context, table string) (map[string][]database.ForeignKey, error) {
 	query := fmt.Sprintf("PRAGMA foreign_key_list(%s)", quoteIdent(table))
 	rows, err := s.db.QueryContext(ctx, query)
@@ -369,7 +603,7 @@ func (s *SQLite) foreignKeys(ctx context.Context, table string) (map[string][]da
 			id, seq  int
 			refTbl   string
 			from     string
-			to       string
+			to       sql.NullString
 			onUpdate string
 			onDelete string
 			match    string
@@ -378,17 +612,32 @@ func (s *SQLite) foreignKeys(ctx context.Context, table string) (map[string][]da
 			return nil, err
 		}
 		fk := database.ForeignKey{
-			RefTable: refTbl,
-			FromCol:  from,
-			ToCol:    to,
-			OnDelete: database.ForeignKeyAction(onDelete),
-			OnUpdate: database.ForeignKeyAction(onUpdate),
+			Constraint: strconv.Itoa(id),
+			RefTable:   refTbl,
+			FromCol:    from,
+			ToCol:      to.String, // Empty when the parent's primary key is referenced implicitly.
+			OnDelete:   database.ForeignKeyAction(onDelete),
+			OnUpdate:   database.ForeignKeyAction(onUpdate),
 		}
 		result[from] = append(result[from], fk)
 	}
 	return result, rows.Err()
 }
 
+// execDDL runs schema statements, or only records them when ctx carries a dry-run plan.
+func (s *SQLite) execDDL(ctx context.Context, stmts ...string) error {
+	if plan := database.PlanFromContext(ctx); plan != nil {
+		plan.Add(stmts...)
+		return nil
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *SQLite) ensureConnected() error {
 	if s.db == nil {
 		return database.ErrNotConnected
@@ -410,7 +659,14 @@ func buildWhere(key database.Key) (string, []any, error) {
 	return strings.Join(clauses, " AND "), args, nil
 }
 
-func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists bool) (string, error) {
+// buildCreateTableSQL renders a CREATE TABLE statement. Extra table constraints are appended after the columns.
+func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists bool, constraints ...string) (string, error) {
+	return createTableSQL(name, columns, ifNotExists, false, constraints...)
+}
+
+// createTableSQL is buildCreateTableSQL with the single primary key column optionally
+// declared AUTOINCREMENT.
+func createTableSQL(name string, columns []database.ColumnDef, ifNotExists, autoincrement bool, constraints ...string) (string, error) {
 	if strings.TrimSpace(name) == "" {
 		return "", fmt.Errorf("table name is required")
 	}
@@ -423,10 +679,23 @@ func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists
 			pkCount++
 		}
 	}
+	if autoincrement && pkCount != 1 {
+		return "", fmt.Errorf("AUTOINCREMENT needs a single primary key column")
+	}
 	var defs []string
 	var pkCols []string
 	for _, col := range columns {
-		def, err := buildColumnDefinition(col, pkCount == 1 && col.PrimaryKey)
+		inlinePK := ""
+		if pkCount == 1 && col.PrimaryKey {
+			inlinePK = "PRIMARY KEY"
+			if autoincrement {
+				if !strings.EqualFold(strings.TrimSpace(col.Type), "INTEGER") {
+					return "", fmt.Errorf("AUTOINCREMENT needs an INTEGER primary key, %s is %s", col.Name, col.Type)
+				}
+				inlinePK += " AUTOINCREMENT"
+			}
+		}
+		def, err := buildColumnDefinition(col, inlinePK)
 		if err != nil {
 			return "", err
 		}
@@ -438,6 +707,7 @@ func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists
 	if len(pkCols) > 1 {
 		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pkCols, ", ")))
 	}
+	defs = append(defs, constraints...)
 	stmt := "CREATE TABLE "
 	if ifNotExists {
 		stmt += "IF NOT EXISTS "
@@ -446,7 +716,9 @@ func buildCreateTableSQL(name string, columns []database.ColumnDef, ifNotExists
 	return stmt, nil
 }
 
-func buildColumnDefinition(col database.ColumnDef, allowInlinePK bool) (string, error) {
+// buildColumnDefinition renders one column; inlinePK is the PRIMARY KEY clause of a
+// single-column primary key, or "" when the key is declared as a table constraint.
+func buildColumnDefinition(col database.ColumnDef, inlinePK string) (string, error) {
 	if strings.TrimSpace(col.Name) == "" || strings.TrimSpace(col.Type) == "" {
 		return "", fmt.Errorf("column name and type are required")
 	}
@@ -457,12 +729,40 @@ func buildColumnDefinition(col database.ColumnDef, allowInlinePK bool) (string,
 	if col.Default != nil {
 		parts = append(parts, "DEFAULT "+*col.Default)
 	}
-	if col.PrimaryKey && allowInlinePK {
-		parts = append(parts, "PRIMARY KEY")
+	if col.PrimaryKey && inlinePK != "" {
+		parts = append(parts, inlinePK)
+	}
+	for _, fk := range col.ForeignKeys {
+		references, err := buildReferences(fk)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, references)
 	}
 	return strings.Join(parts, " "), nil
 }
 
+// buildReferences renders a column-level REFERENCES clause for a single-column foreign key.
+func buildReferences(fk database.ForeignKey) (string, error) {
+	if err := fk.OnUpdate.Check(); err != nil {
+		return "", err
+	}
+	if err := fk.OnDelete.Check(); err != nil {
+		return "", err
+	}
+	clause := "REFERENCES " + quoteIdent(fk.RefTable)
+	if fk.ToCol != "" {
+		clause += fmt.Sprintf(" (%s)", quoteIdent(fk.ToCol))
+	}
+	if fk.OnUpdate != "" {
+		clause += " ON UPDATE " + string(fk.OnUpdate)
+	}
+	if fk.OnDelete != "" {
+		clause += " ON DELETE " + string(fk.OnDelete)
+	}
+	return clause, nil
+}
+
 func orderedKeys(m map[string]any) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
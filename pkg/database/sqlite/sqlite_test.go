@@ -2,7 +2,11 @@ package sqlite
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"sqlite-gui/pkg/database"
 )
@@ -128,6 +132,30 @@ func TestCRUDAndQuery(t *testing.T) {
 	}
 }
 
+func TestReadOnlyQueries(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY); INSERT INTO t VALUES (1), (2)"); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	readOnly := database.WithReadOnly(ctx)
+	if _, err := db.Query(readOnly, "DELETE FROM t WHERE id = 1 RETURNING id"); err == nil {
+		t.Fatalf("expected a read-only query to be unable to write")
+	}
+	if _, err := db.Query(readOnly, "SELECT 1; PRAGMA query_only = 0; DELETE FROM t"); !errors.Is(err, database.ErrMultipleStatements) {
+		t.Fatalf("expected ErrMultipleStatements, got %v", err)
+	}
+	if n, err := db.Count(ctx, "t"); err != nil || n != 2 {
+		t.Fatalf("expected both rows to remain, got %d, %v", n, err)
+	}
+	// The connection is writable again afterwards.
+	if err := db.Insert(ctx, "t", database.Row{"id": 3}); err != nil {
+		t.Fatalf("insert after read-only query: %v", err)
+	}
+}
+
 func TestCompositePrimaryKeyUpdateAndDelete(t *testing.T) {
 	db := newTestDB(t)
 	defer db.Close()
@@ -194,6 +222,334 @@ func TestDDLOperations(t *testing.T) {
 	if err := db.DropTable(ctx, "memberships", true); err != nil {
 		t.Fatalf("drop table: %v", err)
 	}
+
+	injected := database.ColumnDef{Name: "team_id", Type: "INTEGER", ForeignKeys: []database.ForeignKey{
+		{RefTable: "teams", ToCol: "id", OnDelete: "CASCADE; DROP TABLE teams"},
+	}}
+	if err := db.AddColumn(ctx, "users", injected); !errors.Is(err, database.ErrForeignKeyAction) {
+		t.Fatalf("expected ErrForeignKeyAction, got %v", err)
+	}
+}
+
+func TestAlterColumnRebuildsTable(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE parent (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	if _, err := db.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, age TEXT, parent_id INTEGER)`); err != nil {
+		t.Fatalf("create users: %v", err)
+	}
+	if _, err := db.Exec(ctx, `CREATE INDEX users_name ON users (name)`); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO parent (id) VALUES (1); INSERT INTO users (id, name, age, parent_id) VALUES (1, 'alice', '30', 1)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	zero := "0"
+	if err := db.AlterColumn(ctx, "users", "age", database.ColumnDef{Type: "INTEGER", NotNull: true, Default: &zero}); err != nil {
+		t.Fatalf("alter age: %v", err)
+	}
+	fk := database.ForeignKey{RefTable: "parent", ToCol: "id", OnDelete: database.ForeignKeyActionCascade}
+	if err := db.AlterColumn(ctx, "users", "parent_id", database.ColumnDef{Name: "owner_id", Type: "INTEGER", ForeignKeys: []database.ForeignKey{fk}}); err != nil {
+		t.Fatalf("alter parent_id: %v", err)
+	}
+
+	cols, err := db.Columns(ctx, "users")
+	if err != nil {
+		t.Fatalf("columns: %v", err)
+	}
+	byName := map[string]database.Column{}
+	for _, col := range cols {
+		byName[col.Name] = col
+	}
+	age := byName["age"]
+	if age.Type != "INTEGER" || !age.NotNull || age.Default.String != "0" {
+		t.Fatalf("unexpected age column %+v", age)
+	}
+	owner, ok := byName["owner_id"]
+	if !ok || len(owner.ForeignKeys) != 1 || owner.ForeignKeys[0].OnDelete != database.ForeignKeyActionCascade {
+		t.Fatalf("unexpected owner_id column %+v", owner)
+	}
+	if !byName["id"].PrimaryKey {
+		t.Fatalf("id should remain the primary key")
+	}
+
+	rows, err := db.Query(ctx, "SELECT name, age, owner_id FROM users")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["name"] != "alice" || rows[0]["age"] != int64(30) || rows[0]["owner_id"] != int64(1) {
+		t.Fatalf("unexpected rows after rebuild %v", rows)
+	}
+	indexes, err := db.Query(ctx, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'users'")
+	if err != nil {
+		t.Fatalf("indexes: %v", err)
+	}
+	if len(indexes) != 1 || indexes[0]["name"] != "users_name" {
+		t.Fatalf("expected users_name index to be recreated, got %v", indexes)
+	}
+}
+
+func TestAlterTableRejectsForeignKeyViolations(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE parent (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	if _, err := db.Exec(ctx, `CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER)`); err != nil {
+		t.Fatalf("create child: %v", err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO child (id, parent_id) VALUES (1, 42)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	err := db.AlterTable(ctx, "child", []database.ColumnDef{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "parent_id", Type: "INTEGER", ForeignKeys: []database.ForeignKey{{RefTable: "parent", ToCol: "id"}}},
+	})
+	if err == nil {
+		t.Fatalf("expected foreign key violation")
+	}
+	cols, err := db.Columns(ctx, "child")
+	if err != nil {
+		t.Fatalf("columns: %v", err)
+	}
+	for _, col := range cols {
+		if len(col.ForeignKeys) != 0 {
+			t.Fatalf("rebuild should have been rolled back, got %+v", col)
+		}
+	}
+}
+
+func TestAlterTableRejectsBrokenReferences(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `PRAGMA foreign_keys = OFF;
+		CREATE TABLE parent (id INTEGER PRIMARY KEY, code TEXT);
+		CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent (id));
+		INSERT INTO parent (id, code) VALUES (1, 'a');
+		INSERT INTO child (id, parent_id) VALUES (1, 1)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	// Without a primary key the child rows no longer reference anything.
+	err := db.AlterTable(ctx, "parent", []database.ColumnDef{{Name: "code", Type: "TEXT"}})
+	if err == nil {
+		t.Fatalf("expected broken references to be rejected")
+	}
+	cols, err := db.Columns(ctx, "parent")
+	if err != nil {
+		t.Fatalf("columns: %v", err)
+	}
+	if len(cols) != 2 {
+		t.Fatalf("rebuild should have been rolled back, got %+v", cols)
+	}
+}
+
+func TestAlterTableKeepsAutoincrement(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);
+		INSERT INTO items (name) VALUES ('a'), ('b'), ('c');
+		DELETE FROM items WHERE id = 3`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := db.AlterColumn(ctx, "items", "name", database.ColumnDef{Name: "label", Type: "TEXT"}); err != nil {
+		t.Fatalf("alter: %v", err)
+	}
+	rows, err := db.Query(ctx, "SELECT sql FROM sqlite_master WHERE name = 'items'")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if sql, _ := rows[0]["sql"].(string); !strings.Contains(sql, "AUTOINCREMENT") {
+		t.Fatalf("expected AUTOINCREMENT to be kept, got %s", sql)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO items (label) VALUES ('d')"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	rows, err = db.Query(ctx, "SELECT id FROM items WHERE label = 'd'")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["id"] != int64(4) {
+		t.Fatalf("expected the deleted id 3 to stay used, got %v", rows)
+	}
+
+	err = db.AlterColumn(ctx, "items", "id", database.ColumnDef{Type: "TEXT", PrimaryKey: true})
+	if err == nil || !strings.Contains(err.Error(), "AUTOINCREMENT") {
+		t.Fatalf("expected a non-integer AUTOINCREMENT key to be rejected, got %v", err)
+	}
+}
+
+func TestAlterTableKeepsUniqueConstraints(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE accounts (id INTEGER PRIMARY KEY, email TEXT UNIQUE, org INTEGER, handle TEXT, UNIQUE (org, handle))`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if err := db.AlterColumn(ctx, "accounts", "handle", database.ColumnDef{Name: "login", Type: "TEXT"}); err != nil {
+		t.Fatalf("alter: %v", err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO accounts (email, org, login) VALUES ('a@x', 1, 'a')"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO accounts (email, org, login) VALUES ('a@x', 2, 'b')"); err == nil {
+		t.Fatalf("expected the inline UNIQUE on email to be kept")
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO accounts (email, org, login) VALUES ('b@x', 1, 'a')"); err == nil {
+		t.Fatalf("expected UNIQUE (org, handle) to follow the rename")
+	}
+}
+
+func TestAlterTableRefusesWhatItCannotKeep(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	for table, create := range map[string]string{
+		"checked":   "CREATE TABLE checked (id INTEGER PRIMARY KEY, qty INTEGER CHECK (qty > 0))",
+		"collated":  "CREATE TABLE collated (id INTEGER PRIMARY KEY, qty TEXT COLLATE NOCASE)",
+		"generated": "CREATE TABLE generated (id INTEGER PRIMARY KEY, qty INTEGER, twice INTEGER AS (qty * 2))",
+		"rowless":   "CREATE TABLE rowless (id INTEGER PRIMARY KEY, qty INTEGER) WITHOUT ROWID",
+	} {
+		if _, err := db.Exec(ctx, create); err != nil {
+			t.Fatalf("create %s: %v", table, err)
+		}
+		if err := db.AlterColumn(ctx, table, "id", database.ColumnDef{Type: "INTEGER", PrimaryKey: true, NotNull: true}); err == nil {
+			t.Errorf("%s: expected the rebuild to be refused", table)
+		}
+		plan := &database.Plan{}
+		if err := db.AlterColumn(database.WithPlan(ctx, plan), table, "id", database.ColumnDef{Type: "INTEGER", PrimaryKey: true}); err != nil || len(plan.Warnings) == 0 {
+			t.Errorf("%s: expected the plan to warn, got %v %v", table, plan.Warnings, err)
+		}
+	}
+}
+
+func TestRenameTableAndColumn(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := db.Exec(ctx, `CREATE VIEW user_names AS SELECT name FROM users`); err != nil {
+		t.Fatalf("create view: %v", err)
+	}
+
+	deps, err := db.Dependents(ctx, "users", "name")
+	if err != nil {
+		t.Fatalf("dependents: %v", err)
+	}
+	if len(deps) != 1 || deps[0].Type != "view" || deps[0].Name != "user_names" {
+		t.Fatalf("unexpected dependents %v", deps)
+	}
+
+	if err := db.RenameColumn(ctx, "users", "name", "full_name"); err != nil {
+		t.Fatalf("rename column: %v", err)
+	}
+	if err := db.RenameTable(ctx, "users", "accounts"); err != nil {
+		t.Fatalf("rename table: %v", err)
+	}
+	tables, err := db.Tables(ctx)
+	if err != nil {
+		t.Fatalf("tables: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "accounts" {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+	if _, err := db.Query(ctx, "SELECT * FROM user_names"); err != nil {
+		t.Fatalf("view should follow the renames: %v", err)
+	}
+}
+
+func TestPlanRecordsWithoutExecuting(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, age TEXT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	plan := &database.Plan{}
+	planCtx := database.WithPlan(ctx, plan)
+	if err := db.DropTable(planCtx, "users", false); err != nil {
+		t.Fatalf("plan drop table: %v", err)
+	}
+	if err := db.AlterColumn(planCtx, "users", "age", database.ColumnDef{Type: "INTEGER"}); err != nil {
+		t.Fatalf("plan alter column: %v", err)
+	}
+	if len(plan.Statements) == 0 || plan.Statements[0] != `DROP TABLE "users"` {
+		t.Fatalf("unexpected plan %v", plan.Statements)
+	}
+
+	cols, err := db.Columns(ctx, "users")
+	if err != nil {
+		t.Fatalf("columns: %v", err)
+	}
+	if len(cols) != 2 || cols[1].Type != "TEXT" {
+		t.Fatalf("dry run should leave the table untouched, got %+v", cols)
+	}
+}
+
+func TestWatchChangesReportsOtherConnections(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "watch.db")
+
+	watcher, writer := New(), New()
+	for _, db := range []*SQLite{watcher, writer} {
+		if err := db.Connect(ctx, path); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		defer db.Close()
+	}
+	if _, err := writer.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := writer.Exec(ctx, `CREATE TABLE teams (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	changed := make(chan string, 10)
+	watchCtx, stop := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.WatchChanges(watchCtx, func(_, table string) { changed <- table })
+	}()
+	time.Sleep(100 * time.Millisecond) // Let the watcher take its first fingerprints.
+
+	if err := writer.Insert(ctx, "users", database.Row{"name": "alice"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	select {
+	case table := <-changed:
+		if table != "users" {
+			t.Fatalf("expected users to change, got %s", table)
+		}
+	case <-ctx.Done():
+		t.Fatalf("no change reported")
+	}
+	stop()
+	if err := <-done; err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("unexpected extra changes: %d", len(changed))
+	}
 }
 
 func newTestDB(t *testing.T) *SQLite {
@@ -205,3 +561,35 @@ func newTestDB(t *testing.T) *SQLite {
 	}
 	return db
 }
+
+func TestExplainFlagsFullScansAndSorts(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT);
+		CREATE INDEX users_email ON users (email)`); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	plan, err := db.Explain(ctx, "SELECT * FROM users WHERE name > ? ORDER BY name", database.ExplainOptions{}, "x")
+	if err != nil {
+		t.Fatalf("explain scan: %v", err)
+	}
+	var scan, sort bool
+	plan.Walk(func(n *database.PlanNode) {
+		scan = scan || (n.FullScan && n.Relation == "users")
+		sort = sort || n.Sort
+	})
+	if !scan || !sort {
+		t.Fatalf("expected a full scan and a sort, got %+v", plan.Raw)
+	}
+
+	plan, err = db.Explain(ctx, "SELECT * FROM users WHERE email = ?", database.ExplainOptions{}, "a@b.c")
+	if err != nil {
+		t.Fatalf("explain search: %v", err)
+	}
+	if len(plan.Nodes) != 1 || plan.Nodes[0].Type != "SEARCH" || plan.Nodes[0].Index != "users_email" || plan.Nodes[0].FullScan {
+		t.Fatalf("expected an index search, got %+v", plan.Nodes[0])
+	}
+}
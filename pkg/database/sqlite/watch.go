@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+const (
+	changePollInterval = time.Second
+	// fingerprintInterval spaces out the fingerprint scans, which run on the connection
+	// every request shares.
+	fingerprintInterval = 5 * time.Second
+	fingerprintRowLimit = 1000 // Tables up to this many rows are hashed in full.
+)
+
+// WatchChanges polls PRAGMA data_version, which moves whenever another connection commits.
+// On a change it compares per-table fingerprints to tell which tables were modified, at
+// most once every fingerprintInterval. Larger tables are only compared by row count and
+// highest rowid, so an update that keeps both is not reported for them.
+func (s *SQLite) WatchChanges(ctx context.Context, changed func(schema, table string)) error {
+	if err := s.ensureConnected(); err != nil {
+		return err
+	}
+	version, err := s.dataVersion(ctx)
+	if err != nil {
+		return err
+	}
+	prints, err := s.tableFingerprints(ctx)
+	if err != nil {
+		return err
+	}
+	var scanned time.Time // Last scan for a change.
+
+	ticker := time.NewTicker(changePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current, err := s.dataVersion(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if current == version || time.Since(scanned) < fingerprintInterval {
+			continue // A skipped change is picked up by a later tick.
+		}
+		version, scanned = current, time.Now()
+		next, err := s.tableFingerprints(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for table, fp := range next {
+			if prev, ok := prints[table]; !ok || prev != fp {
+				changed("", table)
+			}
+		}
+		for table := range prints {
+			if _, ok := next[table]; !ok {
+				changed("", table)
+			}
+		}
+		prints = next
+	}
+}
+
+func (s *SQLite) dataVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version)
+	return version, err
+}
+
+// tableFingerprints hashes each table's definition, row count and highest rowid, plus its
+// rows when it has at most fingerprintRowLimit of them.
+func (s *SQLite) tableFingerprints(ctx context.Context) (map[string]uint64, error) {
+	defs, err := s.Query(ctx, "SELECT name, sql FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, err
+	}
+	prints := make(map[string]uint64, len(defs))
+	for _, def := range defs {
+		table, create := fmt.Sprint(def["name"]), fmt.Sprint(def["sql"])
+		query := fmt.Sprintf("SELECT count(*), max(rowid) FROM %s", quoteIdent(table))
+		if strings.Contains(strings.ToUpper(create), "WITHOUT ROWID") {
+			query = fmt.Sprintf("SELECT count(*), NULL FROM %s", quoteIdent(table))
+		}
+		var (
+			count int
+			rowid sql.NullInt64
+		)
+		if err := s.db.QueryRowContext(ctx, query).Scan(&count, &rowid); err != nil {
+			return nil, err
+		}
+		h := fnv.New64a()
+		fmt.Fprint(h, create, count, rowid.Int64)
+		if count <= fingerprintRowLimit {
+			rows, err := s.Rows(ctx, table, fingerprintRowLimit, 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				fmt.Fprint(h, row) // Maps print with sorted keys, so this is stable.
+			}
+		}
+		prints[table] = h.Sum64()
+	}
+	return prints, nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// ExecStats receives driver-level measurements of a Query or Exec call: the time until
+// the first row arrived, the result columns in order and, on PostgreSQL, the command tag
+// of the statement.
+type ExecStats struct {
+	FirstRow    time.Duration
+	HasFirstRow bool
+	Columns     []string
+	CommandTag  string
+}
+
+type statsKey struct{}
+
+// WithStats returns a context whose Query and Exec calls record into stats.
+func WithStats(ctx context.Context, stats *ExecStats) context.Context {
+	return context.WithValue(ctx, statsKey{}, stats)
+}
+
+// StatsFromContext returns the stats carried by ctx, or nil when none are collected.
+func StatsFromContext(ctx context.Context) *ExecStats {
+	stats, _ := ctx.Value(statsKey{}).(*ExecStats)
+	return stats
+}
+
+// FirstRowAt records the first row as arriving at time t for a statement started at start.
+// Later calls are ignored; a nil receiver does nothing.
+func (s *ExecStats) FirstRowAt(start, t time.Time) {
+	if s == nil || s.HasFirstRow {
+		return
+	}
+	s.FirstRow = t.Sub(start)
+	s.HasFirstRow = true
+}
+
+// SetColumns records the result columns of a Query call; a nil receiver does nothing.
+func (s *ExecStats) SetColumns(columns []string) {
+	if s != nil {
+		s.Columns = columns
+	}
+}
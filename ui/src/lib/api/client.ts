@@ -10,7 +10,7 @@ if (process.env.NODE_ENV === 'production') {
 const BASE_URL = process.env.NODE_ENV === 'production' ? '/api' : 'http://localhost:3000/api';
 
 export interface ApiOptions<T> {
-	method?: 'GET' | 'POST' | 'PUT' | 'DELETE';
+	method?: 'GET' | 'POST' | 'PUT' | 'PATCH' | 'DELETE';
 	body?: T;
 	headers?: Record<string, string>;
 }